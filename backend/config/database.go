@@ -37,8 +37,8 @@ func InitDatabase() {
 		port = "3306"
 	}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		user, password, host, port, dbname)
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=%s",
+		user, password, host, port, dbname, dbLocation())
 
 	var err error
 	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
@@ -67,11 +67,23 @@ func InitDatabase() {
 	err = DB.AutoMigrate(
 		&models.User{},
 		&models.Siswa{},
+		&models.TodoList{},
+		&models.TodoSeries{},
+		&models.Tag{},
 		&models.Todo{},
+		&models.SavedFilter{},
 		&models.Comment{},
 		&models.MahasiswaGuru{},
 		&models.Assignment{},
 		&models.AssignmentSubmission{},
+		&models.PasswordReset{},
+		&models.RecoveryCode{},
+		&models.Setting{},
+		&models.UserIdentity{},
+		&models.PersonalAccessToken{},
+		&models.Session{},
+		&models.ImpersonationLog{},
+		&models.AuditEvent{},
 	)
 
 	if err != nil {
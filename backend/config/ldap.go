@@ -0,0 +1,298 @@
+package config
+
+import (
+	"bulan2-backend/policy"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAP is the configured directory, or nil when LDAP_URL is not set
+var LDAP *LDAPDirectory
+
+// ErrLDAPInvalidCredentials means the login is unknown to the directory or
+// the password is wrong; the two are deliberately not told apart
+var ErrLDAPInvalidCredentials = errors.New("invalid directory credentials")
+
+// ldapTimeout bounds both connecting and each request to the directory
+const ldapTimeout = 10 * time.Second
+
+// LDAPDirectory authenticates users against an LDAP or Active Directory server
+type LDAPDirectory struct {
+	URL          string
+	StartTLS     bool
+	TLSConfig    *tls.Config
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	// UserFilter finds the user; {login} is replaced with the escaped login
+	UserFilter string
+	IDAttr     string
+	EmailAttr  string
+	NameAttr   string
+	GroupAttr  string
+	// GroupBaseDN and GroupFilter look groups up separately for servers
+	// without memberOf; {dn} is replaced with the user's escaped DN
+	GroupBaseDN string
+	GroupFilter string
+	// GroupRoles maps group DNs or names to roles; the first match wins
+	GroupRoles  []LDAPGroupRole
+	DefaultRole string
+}
+
+// LDAPGroupRole maps one directory group to an application role
+type LDAPGroupRole struct {
+	Group string
+	Role  string
+}
+
+// LDAPEntry is what the directory tells us about an authenticated user
+type LDAPEntry struct {
+	ID     string
+	DN     string
+	Email  string
+	Name   string
+	Groups []string
+}
+
+// InitLDAP configures the directory from LDAP_* variables. Nothing is
+// contacted until the first login, so a directory outage can't stop startup.
+func InitLDAP() {
+	ldapURL := os.Getenv("LDAP_URL")
+	if ldapURL == "" {
+		return
+	}
+
+	dir := &LDAPDirectory{
+		URL:          ldapURL,
+		StartTLS:     os.Getenv("LDAP_START_TLS") == "true",
+		BindDN:       os.Getenv("LDAP_BIND_DN"),
+		BindPassword: os.Getenv("LDAP_BIND_PASSWORD"),
+		BaseDN:       os.Getenv("LDAP_BASE_DN"),
+		UserFilter:   ldapEnv("LDAP_USER_FILTER", "(mail={login})"),
+		IDAttr:       ldapEnv("LDAP_ATTR_ID", "entryUUID"),
+		EmailAttr:    ldapEnv("LDAP_ATTR_EMAIL", "mail"),
+		NameAttr:     ldapEnv("LDAP_ATTR_NAME", "cn"),
+		GroupAttr:    ldapEnv("LDAP_ATTR_GROUPS", "memberOf"),
+		GroupBaseDN:  os.Getenv("LDAP_GROUP_BASE_DN"),
+		GroupFilter:  os.Getenv("LDAP_GROUP_FILTER"),
+		DefaultRole:  ldapEnv("LDAP_DEFAULT_ROLE", policy.RoleUser),
+	}
+	if dir.DefaultRole == "none" {
+		dir.DefaultRole = ""
+	}
+	if dir.DefaultRole != "" && !policy.IsRole(dir.DefaultRole) {
+		log.Printf("LDAP disabled: invalid LDAP_DEFAULT_ROLE %q", dir.DefaultRole)
+		return
+	}
+
+	if os.Getenv("LDAP_INSECURE_SKIP_VERIFY") == "true" {
+		log.Println("WARNING: LDAP certificate verification is disabled")
+		dir.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	// LDAP_GROUP_ROLES is a ;-separated list of <group>=<role>
+	for _, pair := range strings.Split(os.Getenv("LDAP_GROUP_ROLES"), ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		i := strings.LastIndex(pair, "=")
+		if i <= 0 || !policy.IsRole(strings.TrimSpace(pair[i+1:])) {
+			log.Printf("LDAP: ignoring invalid group mapping %q", pair)
+			continue
+		}
+		dir.GroupRoles = append(dir.GroupRoles, LDAPGroupRole{
+			Group: strings.TrimSpace(pair[:i]),
+			Role:  strings.TrimSpace(pair[i+1:]),
+		})
+	}
+
+	if dir.BaseDN == "" {
+		log.Println("LDAP disabled: LDAP_BASE_DN not set")
+		return
+	}
+
+	LDAP = dir
+	log.Printf("LDAP directory configured (%s)", ldapURL)
+}
+
+// Authenticate checks a login and password against the directory and
+// returns the user's entry and group memberships
+func (d *LDAPDirectory) Authenticate(login, password string) (*LDAPEntry, error) {
+	// An empty password would be an unauthenticated bind, which succeeds
+	if login == "" || password == "" {
+		return nil, ErrLDAPInvalidCredentials
+	}
+
+	conn, err := d.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := d.bindService(conn); err != nil {
+		return nil, err
+	}
+
+	filter := strings.ReplaceAll(d.UserFilter, "{login}", ldap.EscapeFilter(login))
+	attrs := []string{d.IDAttr, d.EmailAttr, d.NameAttr, d.GroupAttr}
+	res, err := conn.Search(ldap.NewSearchRequest(
+		d.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(ldapTimeout.Seconds()), false,
+		filter, attrs, nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("user search: %w", err)
+	}
+	if len(res.Entries) == 0 {
+		return nil, ErrLDAPInvalidCredentials
+	}
+	if len(res.Entries) > 1 {
+		return nil, fmt.Errorf("login %q matches more than one directory entry", login)
+	}
+	e := res.Entries[0]
+
+	if err := conn.Bind(e.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrLDAPInvalidCredentials
+		}
+		return nil, fmt.Errorf("user bind: %w", err)
+	}
+
+	entry := &LDAPEntry{
+		ID:     e.GetAttributeValue(d.IDAttr),
+		DN:     e.DN,
+		Email:  strings.ToLower(e.GetAttributeValue(d.EmailAttr)),
+		Name:   e.GetAttributeValue(d.NameAttr),
+		Groups: e.GetAttributeValues(d.GroupAttr),
+	}
+	// Directories without a stable ID attribute fall back to the DN
+	if entry.ID == "" {
+		entry.ID = entry.DN
+	}
+
+	if d.GroupFilter != "" {
+		groups, err := d.searchGroups(conn, e.DN)
+		if err != nil {
+			return nil, err
+		}
+		entry.Groups = append(entry.Groups, groups...)
+	}
+
+	return entry, nil
+}
+
+// RoleFor returns the role for a user in the given groups, or "" when the
+// user is in no mapped group and there is no default role
+func (d *LDAPDirectory) RoleFor(groups []string) string {
+	for _, mapping := range d.GroupRoles {
+		for _, group := range groups {
+			if groupMatches(group, mapping.Group) {
+				return mapping.Role
+			}
+		}
+	}
+	return d.DefaultRole
+}
+
+func (d *LDAPDirectory) dial() (*ldap.Conn, error) {
+	opts := []ldap.DialOpt{ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout})}
+	if d.TLSConfig != nil {
+		opts = append(opts, ldap.DialWithTLSConfig(d.TLSConfig))
+	}
+
+	conn, err := ldap.DialURL(d.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	conn.SetTimeout(ldapTimeout)
+
+	if d.StartTLS {
+		tlsConfig := d.TLSConfig
+		if tlsConfig == nil {
+			u, err := url.Parse(d.URL)
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+			tlsConfig = &tls.Config{ServerName: u.Hostname()}
+		}
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("start tls: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// bindService binds as the search account; without one, searches run anonymously
+func (d *LDAPDirectory) bindService(conn *ldap.Conn) error {
+	if d.BindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(d.BindDN, d.BindPassword); err != nil {
+		return fmt.Errorf("service bind: %w", err)
+	}
+	return nil
+}
+
+// searchGroups finds the groups that list the user as a member
+func (d *LDAPDirectory) searchGroups(conn *ldap.Conn, userDN string) ([]string, error) {
+	// The user may not be allowed to search groups, so switch back first
+	if err := d.bindService(conn); err != nil {
+		return nil, err
+	}
+
+	base := d.GroupBaseDN
+	if base == "" {
+		base = d.BaseDN
+	}
+	filter := strings.ReplaceAll(d.GroupFilter, "{dn}", ldap.EscapeFilter(userDN))
+	res, err := conn.Search(ldap.NewSearchRequest(
+		base, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(ldapTimeout.Seconds()), false,
+		filter, []string{"1.1"}, nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("group search: %w", err)
+	}
+
+	groups := make([]string, 0, len(res.Entries))
+	for _, e := range res.Entries {
+		groups = append(groups, e.DN)
+	}
+	return groups, nil
+}
+
+// groupMatches compares a group DN from the directory with a configured
+// group, which is either a full DN or just the group's name
+func groupMatches(groupDN, configured string) bool {
+	if strings.EqualFold(groupDN, configured) {
+		return true
+	}
+	if strings.Contains(configured, "=") {
+		return false
+	}
+
+	dn, err := ldap.ParseDN(groupDN)
+	if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+		return false
+	}
+	return strings.EqualFold(dn.RDNs[0].Attributes[0].Value, configured)
+}
+
+// ldapEnv reads an LDAP setting, falling back to def when it is unset
+func ldapEnv(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestGroupMatches(t *testing.T) {
+	tests := []struct {
+		groupDN    string
+		configured string
+		want       bool
+	}{
+		{"cn=teachers,ou=groups,dc=bulan2,dc=local", "teachers", true},
+		{"ou=teachers,ou=groups,dc=bulan2,dc=local", "Teachers", true},
+		{"cn=teachers,ou=groups,dc=bulan2,dc=local", "CN=Teachers,OU=Groups,DC=bulan2,DC=local", true},
+		{"cn=teachers,ou=groups,dc=bulan2,dc=local", "cn=teachers,ou=staff,dc=bulan2,dc=local", false},
+		{"cn=students,ou=teachers,dc=bulan2,dc=local", "teachers", false},
+		{"not a dn", "teachers", false},
+	}
+	for _, tt := range tests {
+		if got := groupMatches(tt.groupDN, tt.configured); got != tt.want {
+			t.Errorf("groupMatches(%q, %q) = %v, want %v", tt.groupDN, tt.configured, got, tt.want)
+		}
+	}
+}
+
+func TestRoleFor(t *testing.T) {
+	dir := &LDAPDirectory{
+		GroupRoles: []LDAPGroupRole{
+			{Group: "admins", Role: "admin"},
+			{Group: "teachers", Role: "guru"},
+		},
+		DefaultRole: "user",
+	}
+
+	tests := []struct {
+		name   string
+		groups []string
+		noDef  bool
+		want   string
+	}{
+		{name: "mapped group", groups: []string{"cn=teachers,ou=groups,dc=x"}, want: "guru"},
+		{name: "first mapping wins", groups: []string{"cn=teachers,ou=groups,dc=x", "cn=admins,ou=groups,dc=x"}, want: "admin"},
+		{name: "default role", groups: []string{"cn=clubs,ou=groups,dc=x"}, want: "user"},
+		{name: "no groups", want: "user"},
+		{name: "no default role", groups: []string{"cn=clubs,ou=groups,dc=x"}, noDef: true, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := *dir
+			if tt.noDef {
+				d.DefaultRole = ""
+			}
+			if got := d.RoleFor(tt.groups); got != tt.want {
+				t.Errorf("RoleFor(%v) = %q, want %q", tt.groups, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLDAPAuthenticate runs against the glauth test directory from
+// docker-compose --profile ldap, whose users are listed in ldap/glauth.cfg.
+// Set LDAP_TEST_URL, e.g. ldap://localhost:3893, to run it.
+func TestLDAPAuthenticate(t *testing.T) {
+	url := os.Getenv("LDAP_TEST_URL")
+	if url == "" {
+		t.Skip("LDAP_TEST_URL not set")
+	}
+
+	dir := &LDAPDirectory{
+		URL:          url,
+		BindDN:       "cn=svc-bulan2,ou=svc,dc=bulan2,dc=local",
+		BindPassword: "bulan2-bind",
+		BaseDN:       "dc=bulan2,dc=local",
+		UserFilter:   "(mail={login})",
+		IDAttr:       "entryUUID",
+		EmailAttr:    "mail",
+		NameAttr:     "cn",
+		GroupAttr:    "memberOf",
+		GroupRoles: []LDAPGroupRole{
+			{Group: "admins", Role: "admin"},
+			{Group: "teachers", Role: "guru"},
+			{Group: "students", Role: "user"},
+		},
+	}
+
+	tests := []struct {
+		login    string
+		password string
+		wantRole string
+		wantErr  error
+	}{
+		{login: "admin@bulan2.local", password: "admin123", wantRole: "admin"},
+		{login: "guru@bulan2.local", password: "guru123", wantRole: "guru"},
+		{login: "siswa@bulan2.local", password: "siswa123", wantRole: "user"},
+		{login: "guru@bulan2.local", password: "wrong", wantErr: ErrLDAPInvalidCredentials},
+		{login: "guru@bulan2.local", password: "", wantErr: ErrLDAPInvalidCredentials},
+		{login: "nobody@bulan2.local", password: "guru123", wantErr: ErrLDAPInvalidCredentials},
+		{login: "*", password: "guru123", wantErr: ErrLDAPInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.login, func(t *testing.T) {
+			entry, err := dir.Authenticate(tt.login, tt.password)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate(): %v", err)
+			}
+			if entry.ID == "" || entry.DN == "" {
+				t.Errorf("Authenticate() = %+v, want an ID and DN", entry)
+			}
+			if got := dir.RoleFor(entry.Groups); got != tt.wantRole {
+				t.Errorf("role = %q for groups %v, want %q", got, entry.Groups, tt.wantRole)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+const testClientID = "bulan2-test"
+
+// mockIssuer is a minimal OpenID Connect provider serving discovery and a
+// JWKS with one RSA key, so ID tokens can be minted locally
+type mockIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+	kid string
+	// issuer overrides the issuer published in discovery
+	issuer string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIssuer{key: key, kid: "test-key"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := m.URL
+		if m.issuer != "" {
+			issuer = m.issuer
+		}
+		json.NewEncoder(w).Encode(oidcDiscoveryDocument{
+			Issuer:                issuer,
+			AuthorizationEndpoint: m.URL + "/authorize",
+			TokenEndpoint:         m.URL + "/token",
+			JWKSURI:               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		encode := base64.RawURLEncoding.EncodeToString
+		json.NewEncoder(w).Encode(map[string][]jsonWebKey{"keys": {{
+			Kid: m.kid,
+			Kty: "RSA",
+			Use: "sig",
+			N:   encode(key.N.Bytes()),
+			E:   encode(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mockIssuer) provider() *OIDCProvider {
+	return &OIDCProvider{
+		Name:   "mock",
+		Issuer: m.URL,
+		OAuth2: &oauth2.Config{ClientID: testClientID},
+		Client: m.Client(),
+	}
+}
+
+func (m *mockIssuer) sign(t *testing.T, claims *OIDCClaims, key *rsa.PrivateKey, kid string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	issuer := newMockIssuer(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := func() *OIDCClaims {
+		return &OIDCClaims{
+			Email:         "siswa@bulan2.local",
+			EmailVerified: true,
+			Nonce:         "nonce-1",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    issuer.URL,
+				Subject:   "user-42",
+				Audience:  jwt.ClaimStrings{testClientID},
+				IssuedAt:  jwt.NewNumericDate(time.Now()),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		edit    func(c *OIDCClaims)
+		key     *rsa.PrivateKey
+		kid     string
+		nonce   string
+		wantErr bool
+	}{
+		{name: "valid", edit: func(c *OIDCClaims) {}},
+		{name: "valid without kid", edit: func(c *OIDCClaims) {}, kid: "-"},
+		{name: "extra audience with azp", edit: func(c *OIDCClaims) {
+			c.Audience = append(c.Audience, "other-client")
+			c.AuthorizedParty = testClientID
+		}},
+		{name: "extra audience without azp", edit: func(c *OIDCClaims) {
+			c.Audience = append(c.Audience, "other-client")
+		}, wantErr: true},
+		{name: "wrong nonce", edit: func(c *OIDCClaims) {}, nonce: "nonce-2", wantErr: true},
+		{name: "wrong audience", edit: func(c *OIDCClaims) { c.Audience = jwt.ClaimStrings{"other-client"} }, wantErr: true},
+		{name: "wrong issuer", edit: func(c *OIDCClaims) { c.Issuer = "https://evil.example" }, wantErr: true},
+		{name: "expired", edit: func(c *OIDCClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour)) }, wantErr: true},
+		{name: "no expiry", edit: func(c *OIDCClaims) { c.ExpiresAt = nil }, wantErr: true},
+		{name: "no subject", edit: func(c *OIDCClaims) { c.Subject = "" }, wantErr: true},
+		{name: "signed by another key", edit: func(c *OIDCClaims) {}, key: otherKey, wantErr: true},
+		{name: "unknown kid", edit: func(c *OIDCClaims) {}, kid: "rotated-away", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.edit(claims)
+			key, kid, nonce := issuer.key, issuer.kid, "nonce-1"
+			if tt.key != nil {
+				key = tt.key
+			}
+			switch tt.kid {
+			case "":
+			case "-":
+				kid = ""
+			default:
+				kid = tt.kid
+			}
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+
+			got, err := issuer.provider().VerifyIDToken(context.Background(), issuer.sign(t, claims, key, kid), nonce)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("VerifyIDToken() accepted the token")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyIDToken(): %v", err)
+			}
+			if got.Subject != "user-42" || got.Email != "siswa@bulan2.local" || !bool(got.EmailVerified) {
+				t.Errorf("VerifyIDToken() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestDiscoverRejectsIssuerMismatch(t *testing.T) {
+	issuer := newMockIssuer(t)
+	issuer.issuer = "https://evil.example"
+
+	if err := issuer.provider().Discover(context.Background()); err == nil {
+		t.Fatal("Discover() accepted a document for another issuer")
+	}
+}
+
+func TestFlexibleBool(t *testing.T) {
+	tests := []struct {
+		json string
+		want bool
+	}{
+		{`true`, true},
+		{`"true"`, true},
+		{`false`, false},
+		{`"false"`, false},
+	}
+	for _, tt := range tests {
+		var b flexibleBool
+		if err := json.Unmarshal([]byte(tt.json), &b); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", tt.json, err)
+		}
+		if bool(b) != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, want %v", tt.json, b, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"time"
+
+	// Embed the zone database so LoadLocation works on images without tzdata
+	_ "time/tzdata"
+)
+
+// defaultTimeZone is used for recurring todos that don't name a zone
+var defaultTimeZone = "UTC"
+
+// InitTimeZone reads APP_TIMEZONE, the IANA zone recurring todos follow
+// unless the client sends its own
+func InitTimeZone() {
+	name := os.Getenv("APP_TIMEZONE")
+	if name == "" {
+		return
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		log.Printf("Invalid APP_TIMEZONE %q, using %s: %v", name, defaultTimeZone, err)
+		return
+	}
+	defaultTimeZone = name
+}
+
+// DefaultTimeZone returns the zone name from APP_TIMEZONE, or UTC
+func DefaultTimeZone() string {
+	return defaultTimeZone
+}
+
+// DefaultLocation returns the APP_TIMEZONE location, in which plain dates
+// from clients are read
+func DefaultLocation() *time.Location {
+	loc, err := time.LoadLocation(defaultTimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// dbLocation returns the loc DSN parameter. DATETIME columns carry no zone,
+// so this decides how they map to instants; it stays Local unless
+// DB_TIMEZONE is set, because changing it shifts every stored time.
+func dbLocation() string {
+	name := os.Getenv("DB_TIMEZONE")
+	if name == "" {
+		return "Local"
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		log.Fatalf("Invalid DB_TIMEZONE %q: %v", name, err)
+	}
+	return url.QueryEscape(name)
+}
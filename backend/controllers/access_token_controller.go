@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAccessTokensPerUser keeps token listings manageable
+const maxAccessTokensPerUser = 50
+
+type CreateAccessTokenRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+	// ExpiresInDays defaults to 30
+	ExpiresInDays int `json:"expires_in_days" binding:"omitempty,min=1"`
+}
+
+// GetAccessTokenScopes lists the scopes a personal access token can be granted
+func GetAccessTokenScopes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"scopes": utils.PersonalAccessTokenScopes()})
+}
+
+// GetAccessTokens lists the current user's personal access tokens
+func GetAccessTokens(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var tokens []models.PersonalAccessToken
+	if err := config.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
+		return
+	}
+
+	data := make([]gin.H, 0, len(tokens))
+	for _, t := range tokens {
+		data = append(data, accessTokenResponse(t))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// CreateAccessToken issues a personal access token. The plaintext token is
+// only returned in this response.
+func CreateAccessToken(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seen := map[string]bool{}
+	var scopes []string
+	for _, scope := range req.Scopes {
+		if !utils.IsValidScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope: " + scope})
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	ttl := 30 * 24 * time.Hour
+	if req.ExpiresInDays > 0 {
+		ttl = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	}
+	if ttl > utils.MaxPersonalAccessTokenTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token lifetime is too long"})
+		return
+	}
+
+	var count int64
+	config.DB.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Count(&count)
+	if count >= maxAccessTokensPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many active tokens, revoke some first"})
+		return
+	}
+
+	token, prefix := utils.GeneratePersonalAccessToken()
+	pat := models.PersonalAccessToken{
+		UserID:    userID.(uint),
+		Name:      strings.TrimSpace(req.Name),
+		TokenHash: utils.HashToken(token),
+		Prefix:    prefix,
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	if err := config.DB.Create(&pat).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Token created. Copy it now, it will not be shown again.",
+		"token":   token,
+		"data":    accessTokenResponse(pat),
+	})
+}
+
+// RevokeAccessToken revokes one of the current user's personal access tokens
+func RevokeAccessToken(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var pat models.PersonalAccessToken
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&pat).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+
+	if pat.RevokedAt == nil {
+		if err := config.DB.Model(&pat).Update("revoked_at", time.Now()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked successfully"})
+}
+
+func accessTokenResponse(t models.PersonalAccessToken) gin.H {
+	return gin.H{
+		"id":           t.ID,
+		"name":         t.Name,
+		"prefix":       t.Prefix,
+		"scopes":       t.ScopeList(),
+		"expires_at":   t.ExpiresAt,
+		"last_used_at": t.LastUsedAt,
+		"revoked_at":   t.RevokedAt,
+		"active":       t.IsActive(),
+		"created_at":   t.CreatedAt,
+	}
+}
@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errEmailTaken = errors.New("email already registered")
+
+type UpdateProfileRequest struct {
+	Nama string `json:"nama" binding:"required,max=100"`
+}
+
+type ChangePasswordRequest struct {
+	// CurrentPassword is not needed for accounts that never had one; they
+	// send a 2FA Code instead, if they have 2FA
+	CurrentPassword string `json:"current_password"`
+	Code            string `json:"code"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type ChangeEmailRequest struct {
+	Email           string `json:"email" binding:"required,email,max=100"`
+	CurrentPassword string `json:"current_password"`
+	Code            string `json:"code"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// reauthWindow is how recently an account without a password must have
+// signed in to make sensitive changes without a 2FA code
+const reauthWindow = 10 * time.Minute
+
+// checkCurrentPassword makes sensitive changes require the current password.
+// Accounts without a local password (Google, OIDC, LDAP) give a 2FA code
+// instead, or must have signed in within reauthWindow when they have no 2FA,
+// so a stolen access token alone is not enough. Wrong passwords and codes
+// count towards the same lockout as logins.
+func checkCurrentPassword(c *gin.Context, user *models.User, password, code string) bool {
+	if user.HasPassword() {
+		if !checkLoginLock(c, user.Email) {
+			return false
+		}
+		if !user.CheckPassword(password) {
+			respondCodeFailure(c, user.Email, "Current password is incorrect")
+			return false
+		}
+		return true
+	}
+
+	if user.TOTPEnabled {
+		if !checkLoginLock(c, user.Email) {
+			return false
+		}
+		if !verifySecondFactor(user, code, "") {
+			respondCodeFailure(c, user.Email, "Authentication code is incorrect")
+			return false
+		}
+		return true
+	}
+
+	session, err := utils.GetActiveSession(user.ID, currentSessionID(c))
+	if err != nil || time.Since(session.CreatedAt) > reauthWindow {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":           "Please sign in again to make this change",
+			"reauth_required": true,
+		})
+		return false
+	}
+	return true
+}
+
+// UpdateProfile changes the current user's display name
+func UpdateProfile(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	nama := strings.TrimSpace(req.Nama)
+	if nama == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := config.DB.Model(&user).Update("nama", nama).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile updated successfully",
+		"data":    user,
+	})
+}
+
+// ChangePassword sets a new password and signs out every other session.
+// Accounts created through an external login use it to set a first password.
+func ChangePassword(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !checkCurrentPassword(c, &user, req.CurrentPassword, req.Code) {
+		return
+	}
+
+	firstPassword := !user.HasPassword()
+	user.Password = req.NewPassword
+	if err := user.HashPassword(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	if err := config.DB.Model(&user).Updates(map[string]interface{}{
+		"password":     user.Password,
+		"passwordless": false,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	recordAudit(c, utils.AuditPasswordChange, "user", user.ID, nil, nil)
+
+	revoked, err := utils.RevokeOtherSessions(user.ID, currentSessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password changed but failed to sign out other sessions"})
+		return
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nThe password for your account was just changed. If this wasn't you, reset your password right away.\n", user.Nama)
+	if firstPassword {
+		body = fmt.Sprintf("Hi %s,\n\nA password was just set for your account, so you can now also log in with your email and password. If this wasn't you, reset your password right away.\n", user.Nama)
+	}
+	if err := config.Mail.Send(user.Email, "Your password was changed", body); err != nil {
+		log.Printf("Failed to send password change email to user %d: %v", user.ID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Password changed successfully",
+		"revoked_sessions": revoked,
+	})
+}
+
+// ChangeEmail starts an email change. The address only changes once the
+// link sent to the new address is opened.
+func ChangeEmail(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !checkCurrentPassword(c, &user, req.CurrentPassword, req.Code) {
+		return
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if strings.EqualFold(email, user.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This is already your email address"})
+		return
+	}
+
+	var existing models.User
+	if err := config.DB.Unscoped().Where("email = ?", email).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	}
+
+	// Only the most recent request can be confirmed
+	if err := config.DB.Model(&user).Update("pending_email", email).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change email"})
+		return
+	}
+
+	token := utils.GenerateSignedToken(utils.PurposeEmailChange, user.ID, email, utils.EmailVerificationTTL)
+	link := fmt.Sprintf("%s/verify-email?change=1&token=%s", getFrontendURL(), url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm your new email address by opening the link below:\n\n%s\n\nThe link expires in %s. Until then you keep using your current address.\n",
+		user.Nama, link, utils.EmailVerificationTTL)
+	if err := config.Mail.Send(email, "Confirm your new email address", body); err != nil {
+		log.Printf("Failed to send email change link to user %d: %v", user.ID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "A confirmation link has been sent to your new email address",
+		"pending_email": email,
+	})
+}
+
+// ConfirmEmailChange swaps in the pending email using the signed link token
+func ConfirmEmailChange(c *gin.Context) {
+	var req ConfirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, email, err := utils.ParseSignedToken(utils.PurposeEmailChange, req.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired confirmation link"})
+		return
+	}
+
+	var user models.User
+	var oldEmail string
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND pending_email = ?", userID, email).First(&user).Error; err != nil {
+			return err
+		}
+
+		var existing models.User
+		if err := tx.Unscoped().Where("email = ? AND id <> ?", email, user.ID).First(&existing).Error; err == nil {
+			return errEmailTaken
+		}
+
+		oldEmail = user.Email
+		return tx.Model(&user).Updates(map[string]interface{}{
+			"email":         email,
+			"pending_email": "",
+			"verified_at":   time.Now(),
+		}).Error
+	})
+	if err == errEmailTaken {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired confirmation link"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change email"})
+		return
+	}
+
+	// Let the old address know in case the change wasn't made by its owner
+	body := fmt.Sprintf("Hi %s,\n\nThe email address for your account was changed to %s. If this wasn't you, contact an administrator right away.\n", user.Nama, email)
+	if err := config.Mail.Send(oldEmail, "Your email address was changed", body); err != nil {
+		log.Printf("Failed to send email change notice to user %d: %v", user.ID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email changed successfully"})
+}
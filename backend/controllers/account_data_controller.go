@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeleteAccountRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Code            string `json:"code"`
+}
+
+// accountDeletionGrace returns how long a deletion request can be cancelled
+func accountDeletionGrace() time.Duration {
+	if grace, err := time.ParseDuration(os.Getenv("ACCOUNT_DELETION_GRACE")); err == nil && grace > 0 {
+		return grace
+	}
+	return 14 * 24 * time.Hour
+}
+
+// ExportMyData streams a zip archive with everything stored about the user:
+// the profile, one JSON file per data source and the uploaded files
+func ExportMyData(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Load everything before writing so errors can still get a proper status
+	data := map[string]interface{}{}
+	for _, src := range utils.UserDataSources {
+		if src.Export == nil {
+			continue
+		}
+		rows, err := src.Export(config.DB, user.ID)
+		if err != nil {
+			log.Printf("Export of %s for user %d failed: %v", src.Name, user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export data"})
+			return
+		}
+		data[src.Name] = rows
+	}
+
+	recordAudit(c, utils.AuditAccountExport, "user", user.ID, nil, nil)
+
+	files, err := utils.UserFiles(config.DB, &user)
+	if err != nil {
+		log.Printf("Export of files for user %d failed: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export data"})
+		return
+	}
+
+	filename := fmt.Sprintf("bulan2-export-%d-%s.zip", user.ID, time.Now().Format("20060102"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+
+	zw := zip.NewWriter(c.Writer)
+	defer zw.Close()
+
+	writeJSON := func(name string, v interface{}) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	if err := writeJSON("profile.json", gin.H{
+		"id":            user.ID,
+		"nama":          user.Nama,
+		"email":         user.Email,
+		"role":          user.Role,
+		"status":        user.Status,
+		"foto":          user.Foto,
+		"verified_at":   user.VerifiedAt,
+		"totp_enabled":  user.TOTPEnabled,
+		"has_password":  user.HasPassword(),
+		"pending_email": user.PendingEmail,
+		"created_at":    user.CreatedAt,
+		"updated_at":    user.UpdatedAt,
+		"exported_at":   time.Now(),
+	}); err != nil {
+		log.Printf("Export for user %d failed: %v", user.ID, err)
+		return
+	}
+
+	for _, src := range utils.UserDataSources {
+		if rows, ok := data[src.Name]; ok {
+			if err := writeJSON(src.Name+".json", rows); err != nil {
+				log.Printf("Export for user %d failed: %v", user.ID, err)
+				return
+			}
+		}
+	}
+
+	for _, p := range files {
+		if err := addFileToZip(zw, p, path.Join("files", filepath.Base(filepath.Dir(p)), filepath.Base(p))); err != nil {
+			// A missing upload shouldn't break the whole export
+			log.Printf("Export for user %d: skipping %s: %v", user.ID, p, err)
+		}
+	}
+}
+
+func addFileToZip(zw *zip.Writer, src, name string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// RequestAccountDeletion schedules the account to be purged after the grace
+// period and signs the user out everywhere. Logging in again and calling
+// CancelAccountDeletion keeps the account.
+func RequestAccountDeletion(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !checkCurrentPassword(c, &user, req.CurrentPassword, req.Code) {
+		return
+	}
+
+	deleteAfter := time.Now().Add(accountDeletionGrace())
+	if err := config.DB.Model(&user).Update("delete_after", deleteAfter).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule account deletion"})
+		return
+	}
+
+	recordAudit(c, utils.AuditAccountDelete, "user", user.ID, nil, gin.H{"delete_after": deleteAfter})
+
+	if err := utils.RevokeUserTokens(user.ID); err != nil {
+		log.Printf("Failed to sign out user %d after deletion request: %v", user.ID, err)
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nYour account and all of its data will be permanently deleted on %s. If you change your mind, log in before then and cancel the deletion.\n",
+		user.Nama, deleteAfter.Format("2006-01-02 15:04 MST"))
+	if err := config.Mail.Send(user.Email, "Your account is scheduled for deletion", body); err != nil {
+		log.Printf("Failed to send deletion email to user %d: %v", user.ID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Account scheduled for deletion",
+		"delete_after": deleteAfter,
+	})
+}
+
+// CancelAccountDeletion keeps an account that was scheduled for deletion
+func CancelAccountDeletion(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	result := config.DB.Model(&models.User{}).
+		Where("id = ? AND delete_after IS NOT NULL", userID).
+		Update("delete_after", nil)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel account deletion"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account is not scheduled for deletion"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deletion cancelled"})
+}
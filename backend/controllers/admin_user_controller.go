@@ -0,0 +1,294 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UnlockAccountRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type AdminCreateUserRequest struct {
+	Nama     string `json:"nama" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=6"`
+	Role     string `json:"role" binding:"required,oneof=admin guru user"`
+}
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin guru user"`
+}
+
+// GetUsers lists users with search, filters and pagination
+func GetUsers(c *gin.Context) {
+	// Pagination params
+	page := utils.ParseInt(c.DefaultQuery("page", "1"), 1)
+	limit := utils.ParseInt(c.DefaultQuery("limit", "20"), 20)
+	if limit > 100 {
+		limit = 100
+	}
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.User{})
+
+	// deleted=include shows soft-deleted users too, deleted=only shows just them
+	switch c.Query("deleted") {
+	case "include":
+		query = query.Unscoped()
+	case "only":
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+
+	if search := c.Query("search"); search != "" {
+		query = query.Where("nama LIKE ? OR email LIKE ?", "%"+search+"%", "%"+search+"%")
+	}
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var users []models.User
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	totalPages := (int(total) + limit - 1) / limit
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": users,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+// GetUser returns a single user, including soft-deleted ones
+func GetUser(c *gin.Context) {
+	var user models.User
+	if err := config.DB.Unscoped().First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    user,
+		"deleted": user.DeletedAt.Valid,
+	})
+}
+
+// CreateUser lets an admin create an account with any role
+func CreateUser(c *gin.Context) {
+	var req AdminCreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existingUser models.User
+	if err := config.DB.Unscoped().Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	}
+
+	// Accounts created by an admin are trusted, so they start verified
+	now := time.Now()
+	user := models.User{
+		Nama:       req.Nama,
+		Email:      req.Email,
+		Role:       req.Role,
+		Status:     "active",
+		VerifiedAt: &now,
+	}
+
+	user.Password = req.Password
+	if err := user.HashPassword(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	if err := config.DB.Create(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
+	recordAudit(c, utils.AuditUserCreate, "user", user.ID, nil, gin.H{"email": user.Email, "role": user.Role})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "User created successfully",
+		"data":    user,
+	})
+}
+
+// UpdateUserRole changes a user's role. Existing tokens are revoked so the
+// new role applies on the user's next refresh instead of after expiry.
+func UpdateUserRole(c *gin.Context) {
+	adminID, _ := c.Get("user_id")
+
+	var req UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.ID == adminID.(uint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot change your own role"})
+		return
+	}
+
+	oldRole := user.Role
+	if err := config.DB.Model(&user).Update("role", req.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	recordAudit(c, utils.AuditUserRoleChange, "user", user.ID, gin.H{"role": oldRole}, gin.H{"role": user.Role})
+
+	if err := utils.RevokeUserTokens(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Role updated but failed to revoke existing sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role updated successfully",
+		"data":    user,
+	})
+}
+
+// SuspendUser blocks a user from signing in and ends their sessions
+func SuspendUser(c *gin.Context) {
+	setUserStatus(c, "suspended")
+}
+
+// ReactivateUser lifts a suspension
+func ReactivateUser(c *gin.Context) {
+	setUserStatus(c, "active")
+}
+
+func setUserStatus(c *gin.Context, status string) {
+	adminID, _ := c.Get("user_id")
+
+	var user models.User
+	if err := config.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.ID == adminID.(uint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot change your own status"})
+		return
+	}
+
+	oldStatus := user.Status
+	if err := config.DB.Model(&user).Update("status", status).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user status"})
+		return
+	}
+
+	action := utils.AuditUserReactivate
+	if status == "suspended" {
+		action = utils.AuditUserSuspend
+	}
+	recordAudit(c, action, "user", user.ID, gin.H{"status": oldStatus}, gin.H{"status": status})
+
+	if status == "suspended" {
+		if err := utils.RevokeUserTokens(user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "User suspended but failed to revoke existing sessions"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User status updated successfully",
+		"data":    user,
+	})
+}
+
+// DeleteUser soft-deletes a user and ends their sessions
+func DeleteUser(c *gin.Context) {
+	adminID, _ := c.Get("user_id")
+
+	var user models.User
+	if err := config.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.ID == adminID.(uint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot delete your own account here"})
+		return
+	}
+
+	if err := config.DB.Delete(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+
+	recordAudit(c, utils.AuditUserDelete, "user", user.ID, gin.H{"email": user.Email, "role": user.Role}, nil)
+
+	if err := utils.RevokeUserTokens(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User deleted but failed to revoke existing sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+}
+
+// RestoreUser undoes a soft delete
+func RestoreUser(c *gin.Context) {
+	var user models.User
+	if err := config.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted user not found"})
+		return
+	}
+
+	if err := config.DB.Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore user"})
+		return
+	}
+
+	recordAudit(c, utils.AuditUserRestore, "user", user.ID, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User restored successfully",
+		"data":    user,
+	})
+}
+
+// UnlockAccount clears failed login attempts and any lockout for an account
+func UnlockAccount(c *gin.Context) {
+	var req UnlockAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := utils.ResetLoginFailures(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock account"})
+		return
+	}
+
+	recordAudit(c, utils.AuditUserUnlock, "user", 0, nil, gin.H{"email": req.Email})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked successfully"})
+}
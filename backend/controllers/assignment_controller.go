@@ -3,6 +3,8 @@ package controllers
 import (
 	"bulan2-backend/config"
 	"bulan2-backend/models"
+	"bulan2-backend/policy"
+	"bulan2-backend/utils"
 	"net/http"
 	"strconv"
 	"time"
@@ -13,9 +15,8 @@ import (
 // CreateAssignment - Guru buat tugas baru
 func CreateAssignment(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	role, _ := c.Get("role")
 
-	if role != "guru" {
+	if !policy.Can(c.GetString("role"), policy.AssignmentManage) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Hanya guru yang bisa membuat tugas"})
 		return
 	}
@@ -74,9 +75,8 @@ func CreateAssignment(c *gin.Context) {
 // GetGuruAssignments - Guru lihat semua tugas yang dibuat
 func GetGuruAssignments(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	role, _ := c.Get("role")
 
-	if role != "guru" {
+	if !policy.Can(c.GetString("role"), policy.AssignmentManage) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Hanya guru yang bisa akses endpoint ini"})
 		return
 	}
@@ -93,9 +93,8 @@ func GetGuruAssignments(c *gin.Context) {
 // GetAssignmentSubmissions - Guru lihat submissions untuk tugas tertentu
 func GetAssignmentSubmissions(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	role, _ := c.Get("role")
 
-	if role != "guru" {
+	if !policy.Can(c.GetString("role"), policy.AssignmentManage) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Hanya guru yang bisa akses endpoint ini"})
 		return
 	}
@@ -126,9 +125,8 @@ func GetAssignmentSubmissions(c *gin.Context) {
 // GradeSubmission - Guru beri nilai untuk submission
 func GradeSubmission(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	role, _ := c.Get("role")
 
-	if role != "guru" {
+	if !policy.Can(c.GetString("role"), policy.AssignmentGrade) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Hanya guru yang bisa memberi nilai"})
 		return
 	}
@@ -162,6 +160,8 @@ func GradeSubmission(c *gin.Context) {
 		return
 	}
 
+	before := gin.H{"grade": submission.Grade, "feedback": submission.Feedback, "status": submission.Status}
+
 	submission.Grade = &request.Grade
 	submission.Feedback = request.Feedback
 	submission.Status = "graded"
@@ -171,6 +171,9 @@ func GradeSubmission(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, utils.AuditSubmissionGrade, "assignment_submission", submission.ID, before,
+		gin.H{"grade": submission.Grade, "feedback": submission.Feedback, "status": submission.Status})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Nilai berhasil diberikan",
 		"data":    submission,
@@ -180,9 +183,8 @@ func GradeSubmission(c *gin.Context) {
 // DeleteAssignment - Guru hapus tugas
 func DeleteAssignment(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	role, _ := c.Get("role")
 
-	if role != "guru" {
+	if !policy.Can(c.GetString("role"), policy.AssignmentManage) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Hanya guru yang bisa hapus tugas"})
 		return
 	}
@@ -205,6 +207,8 @@ func DeleteAssignment(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, utils.AuditAssignmentDelete, "assignment", assignment.ID, gin.H{"title": assignment.Title}, nil)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Tugas berhasil dihapus"})
 }
 
@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/utils"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// recordAudit writes an audit event for the authenticated user's action,
+// taking the actor, request ID and IP from the request context
+func recordAudit(c *gin.Context, action, targetType string, targetID uint, before, after interface{}) {
+	recordAuditAs(c, c.GetUint("user_id"), action, targetType, targetID, before, after)
+}
+
+// recordAuditAs is recordAudit for requests that are not authenticated yet,
+// such as logins, where the actor is known from the request body
+func recordAuditAs(c *gin.Context, actorID uint, action, targetType string, targetID uint, before, after interface{}) {
+	utils.RecordAudit(utils.AuditEntry{
+		ActorID:        actorID,
+		ImpersonatorID: c.GetUint("impersonator_id"),
+		Action:         action,
+		TargetType:     targetType,
+		TargetID:       targetID,
+		Before:         before,
+		After:          after,
+		RequestID:      c.GetString("request_id"),
+		IPAddress:      c.ClientIP(),
+	})
+}
+
+// auditEventsQuery applies the filters shared by the list and CSV export
+func auditEventsQuery(c *gin.Context) (*gorm.DB, error) {
+	query := config.DB.Model(&models.AuditEvent{})
+
+	if actorID := c.Query("actor_id"); actorID != "" {
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if action := c.Query("action"); action != "" {
+		// "user." matches every user action
+		if strings.HasSuffix(action, ".") {
+			query = query.Where("action LIKE ?", action+"%")
+		} else {
+			query = query.Where("action = ?", action)
+		}
+	}
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID := c.Query("target_id"); targetID != "" {
+		query = query.Where("target_id = ?", targetID)
+	}
+	if requestID := c.Query("request_id"); requestID != "" {
+		query = query.Where("request_id = ?", requestID)
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := parseTimeParam(from)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("created_at >= ?", t)
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := parseTimeParam(to)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("created_at < ?", t)
+	}
+
+	return query, nil
+}
+
+// parseTimeParam accepts RFC 3339 timestamps or plain dates
+func parseTimeParam(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, use RFC 3339 or YYYY-MM-DD", s)
+	}
+	return t, nil
+}
+
+// GetAuditEvents lists audit events, newest first, with optional filters
+func GetAuditEvents(c *gin.Context) {
+	// Pagination params
+	page := utils.ParseInt(c.DefaultQuery("page", "1"), 1)
+	limit := utils.ParseInt(c.DefaultQuery("limit", "50"), 50)
+	if limit > 200 {
+		limit = 200
+	}
+	offset := (page - 1) * limit
+
+	query, err := auditEventsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var events []models.AuditEvent
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit events"})
+		return
+	}
+
+	totalPages := (int(total) + limit - 1) / limit
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": events,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+// ExportAuditEventsCSV streams the filtered audit events as CSV
+func ExportAuditEventsCSV(c *gin.Context) {
+	query, err := auditEventsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Exports are recorded before they run, so a failed export still shows up
+	recordAudit(c, utils.AuditAuditExport, "audit_event", 0, nil, c.Request.URL.Query())
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", "attachment; filename=audit-events.csv")
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"id", "created_at", "actor_id", "impersonator_id", "action", "target_type", "target_id", "before", "after", "request_id", "ip_address"})
+
+	var batch []models.AuditEvent
+	query.Order("id").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for _, e := range batch {
+			w.Write([]string{
+				fmt.Sprint(e.ID),
+				e.CreatedAt.UTC().Format(time.RFC3339),
+				csvID(e.ActorID),
+				csvID(e.ImpersonatorID),
+				e.Action,
+				e.TargetType,
+				fmt.Sprint(e.TargetID),
+				e.Before,
+				e.After,
+				e.RequestID,
+				e.IPAddress,
+			})
+		}
+		w.Flush()
+		return w.Error()
+	})
+	w.Flush()
+}
+
+// csvID formats an optional ID for CSV, leaving it empty when unset
+func csvID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return fmt.Sprint(*id)
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/utils"
+	"errors"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Password backends, listed in AUTH_BACKEND in the order they are tried
+const (
+	authBackendLocal = "local"
+	authBackendLDAP  = "ldap"
+)
+
+// ldapProvider is the UserIdentity provider for directory accounts
+const ldapProvider = "ldap"
+
+var (
+	errInvalidCredentials = errors.New("invalid email or password")
+	// errDirectoryUnavailable means no backend accepted the login and the
+	// directory could not be asked, so the password may well be right
+	errDirectoryUnavailable = errors.New("directory unavailable")
+	errDirectoryNoRole      = errors.New("directory account is not in any allowed group")
+)
+
+// authBackends returns the configured password backends, defaulting to the
+// local password hash
+func authBackends() []string {
+	var backends []string
+	for _, b := range strings.Split(os.Getenv("AUTH_BACKEND"), ",") {
+		switch b = strings.TrimSpace(strings.ToLower(b)); b {
+		case authBackendLocal:
+			backends = append(backends, b)
+		case authBackendLDAP:
+			if config.LDAP != nil {
+				backends = append(backends, b)
+			}
+		}
+	}
+	if len(backends) == 0 {
+		return []string{authBackendLocal}
+	}
+	return backends
+}
+
+// authenticatePassword checks an email and password against each backend in
+// turn and returns the user from the first one that accepts them
+func authenticatePassword(c *gin.Context, email, password string) (*models.User, error) {
+	unavailable := false
+
+	for _, backend := range authBackends() {
+		var user *models.User
+		var err error
+		switch backend {
+		case authBackendLocal:
+			user, err = authenticateLocal(email, password)
+		case authBackendLDAP:
+			user, err = authenticateLDAP(c, email, password)
+		}
+
+		switch {
+		case err == nil:
+			return user, nil
+		case errors.Is(err, errInvalidCredentials):
+			continue
+		case errors.Is(err, errDirectoryUnavailable):
+			unavailable = true
+			continue
+		default:
+			return nil, err
+		}
+	}
+
+	if unavailable {
+		return nil, errDirectoryUnavailable
+	}
+	return nil, errInvalidCredentials
+}
+
+// authenticateLocal checks the bcrypt hash stored on the user
+func authenticateLocal(email, password string) (*models.User, error) {
+	var user models.User
+	if err := config.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, errInvalidCredentials
+	}
+	if !user.CheckPassword(password) {
+		return nil, errInvalidCredentials
+	}
+	return &user, nil
+}
+
+// authenticateLDAP binds to the directory as the user, provisions the
+// account on first login and keeps its role in sync with directory groups.
+// An existing local account is only linked when it has the default role, so
+// the directory never overwrites a role that was given in the app.
+func authenticateLDAP(c *gin.Context, email, password string) (*models.User, error) {
+	entry, err := config.LDAP.Authenticate(email, password)
+	if errors.Is(err, config.ErrLDAPInvalidCredentials) {
+		return nil, errInvalidCredentials
+	}
+	if err != nil {
+		log.Printf("LDAP login for %s failed: %v", email, err)
+		return nil, errDirectoryUnavailable
+	}
+
+	role := config.LDAP.RoleFor(entry.Groups)
+	if role == "" {
+		return nil, errDirectoryNoRole
+	}
+
+	if entry.Email == "" {
+		entry.Email = strings.ToLower(email)
+	}
+	user, err := resolveExternalUser(externalAccount{
+		Provider: ldapProvider,
+		Subject:  entry.ID,
+		Email:    entry.Email,
+		// The school runs the directory, so its addresses are trusted
+		EmailVerified:   true,
+		Name:            entry.Name,
+		DefaultRoleOnly: true,
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// The linked account was deleted
+		return nil, errInvalidCredentials
+	}
+	if errors.Is(err, errLinkRoleConflict) {
+		log.Printf("LDAP login for %s refused: the local account has a role the directory does not manage", email)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Role != role {
+		oldRole := user.Role
+		if err := config.DB.Model(user).Update("role", role).Error; err != nil {
+			return nil, err
+		}
+		// Sessions that still carry the old role must not outlive the change
+		if err := utils.RevokeUserTokens(user.ID); err != nil {
+			log.Printf("Failed to revoke sessions of user %d after LDAP role change: %v", user.ID, err)
+		}
+		recordAuditAs(c, 0, utils.AuditUserRoleChange, "user", user.ID,
+			gin.H{"role": oldRole}, gin.H{"role": role, "source": ldapProvider})
+	}
+	if entry.Name != "" && user.Nama != entry.Name {
+		if err := config.DB.Model(user).Update("nama", entry.Name).Error; err != nil {
+			log.Printf("Failed to sync name of user %d from LDAP: %v", user.ID, err)
+		}
+	}
+
+	return user, nil
+}
@@ -3,17 +3,19 @@ package controllers
 import (
 	"bulan2-backend/config"
 	"bulan2-backend/models"
+	"bulan2-backend/policy"
 	"bulan2-backend/utils"
 	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -29,6 +31,14 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 // Register handles user registration
 func Register(c *gin.Context) {
 	var req RegisterRequest
@@ -48,7 +58,7 @@ func Register(c *gin.Context) {
 	user := models.User{
 		Nama:  req.Nama,
 		Email: req.Email,
-		Role:  "user",
+		Role:  policy.RoleUser,
 	}
 
 	// Hash password
@@ -64,13 +74,16 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	sendVerificationEmail(&user)
+
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Registration successful",
+		"message": "Registration successful. Please check your email to verify your account",
 		"user": gin.H{
-			"id":    user.ID,
-			"nama":  user.Nama,
-			"email": user.Email,
-			"role":  user.Role,
+			"id":       user.ID,
+			"nama":     user.Nama,
+			"email":    user.Email,
+			"role":     user.Role,
+			"verified": user.IsVerified(),
 		},
 	})
 }
@@ -83,39 +96,215 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Find user by email
-	var user models.User
-	if err := config.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+	// Refuse accounts locked by too many failed attempts
+	if remaining, err := utils.LoginLockRemaining(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check account status"})
+		return
+	} else if remaining > 0 {
+		respondAccountLocked(c, remaining)
+		return
+	}
+
+	// Check the password against the configured backends
+	user, err := authenticatePassword(c, req.Email, req.Password)
+	switch {
+	case errors.Is(err, errInvalidCredentials):
+		// Unknown emails count too, so lockouts don't reveal which accounts exist
+		respondLoginFailure(c, req.Email)
+		return
+	case errors.Is(err, errDirectoryNoRole):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your directory account is not allowed to use this application"})
+		return
+	case errors.Is(err, errLinkRoleConflict):
+		c.JSON(http.StatusForbidden, gin.H{"error": "An account with this email already exists, please ask an administrator to link it to your directory account"})
+		return
+	case errors.Is(err, errDirectoryUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Login is temporarily unavailable, please try again later"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
 		return
 	}
 
-	// Check password
-	if !user.CheckPassword(req.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+	if !checkCanLogin(c, user) {
+		return
+	}
+
+	// Two-factor users finish logging in with a code
+	if purpose := mfaPurpose(user); purpose != "" {
+		respondMFAChallenge(c, user, purpose)
+		return
+	}
+
+	// Only a complete login clears the counter; a right password alone
+	// must not reset the guesses made at the 2FA step
+	utils.ResetLoginFailures(req.Email)
+
+	respondLoginSuccess(c, user)
+}
+
+// checkCanLogin refuses suspended accounts and, under the block policy,
+// unverified ones. Every way of signing in goes through it.
+func checkCanLogin(c *gin.Context, user *models.User) bool {
+	if !user.IsActive() {
+		respondAccountSuspended(c)
+		return false
+	}
+
+	// Enforce email verification policy
+	if !user.IsVerified() && utils.EmailVerificationPolicy() == utils.VerificationPolicyBlock {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Please verify your email address before logging in",
+			"code":  "email_unverified",
+		})
+		return false
+	}
+	return true
+}
+
+// respondLoginFailure records a failed attempt for the account and answers
+// with the generic error, or with a lockout once the limit is reached
+func respondLoginFailure(c *gin.Context, email string) {
+	// The audit log keeps the account that was tried, never the typed email
+	var user models.User
+	if err := config.DB.Select("id").Where("email = ?", email).First(&user).Error; err == nil {
+		recordAuditAs(c, 0, utils.AuditLoginFailed, "user", user.ID, nil, nil)
+	} else {
+		recordAuditAs(c, 0, utils.AuditLoginFailed, "user", 0, nil, gin.H{"email_hash": utils.EmailHash(email)})
+	}
+
+	lock, err := utils.RecordLoginFailure(email)
+	if err == nil && lock > 0 {
+		respondAccountLocked(c, lock)
 		return
 	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+}
+
+// respondAccountSuspended refuses to sign in a suspended user
+func respondAccountSuspended(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error": "Your account has been suspended",
+		"code":  "account_suspended",
+	})
+}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID, user.Email, user.Role)
+// respondAccountLocked tells the client how long the account stays locked
+func respondAccountLocked(c *gin.Context, remaining time.Duration) {
+	seconds := int(remaining.Seconds()) + 1
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many failed login attempts. Account temporarily locked",
+		"retry_after": seconds,
+	})
+}
+
+// respondLoginSuccess issues access and refresh tokens for a fully authenticated user
+func respondLoginSuccess(c *gin.Context, user *models.User) {
+	token, refreshToken, err := issueTokens(c, user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   token,
+		"message":       "Login successful",
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(utils.AccessTokenTTL.Seconds()),
 		"user": gin.H{
-			"id":    user.ID,
-			"nama":  user.Nama,
-			"email": user.Email,
-			"role":  user.Role,
-			"foto":  user.Foto,
+			"id":           user.ID,
+			"nama":         user.Nama,
+			"email":        user.Email,
+			"role":         user.Role,
+			"foto":         user.Foto,
+			"verified":     user.IsVerified(),
+			"totp_enabled": user.TOTPEnabled,
 		},
 	})
 }
 
+// RefreshToken rotates a refresh token and issues a new access token
+func RefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, sessionID, err := utils.ConsumeRefreshToken(req.RefreshToken)
+	if err == utils.ErrInvalidRefreshToken {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	// Reload the user so role changes, suspensions and deletions apply on refresh
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+	if !user.IsActive() {
+		respondAccountSuspended(c)
+		return
+	}
+
+	// A signed-out session can't be refreshed back to life
+	if _, err := utils.GetActiveSession(user.ID, sessionID); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been signed out"})
+		return
+	}
+	utils.TouchSession(sessionID, c.ClientIP())
+
+	token, refreshToken, err := issueSessionTokens(&user, sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(utils.AccessTokenTTL.Seconds()),
+	})
+}
+
+// Logout ends the current session and revokes the given refresh token, if
+// it belongs to the signed-in user
+func Logout(c *gin.Context) {
+	var req LogoutRequest
+	// Body is optional; without it only the access token and session are revoked
+	_ = c.ShouldBindJSON(&req)
+
+	if value, ok := c.Get("claims"); ok {
+		claims := value.(*utils.Claims)
+		if err := utils.RevokeAccessToken(claims); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+			return
+		}
+
+		if session, err := utils.GetActiveSession(claims.UserID, claims.SessionID); err == nil {
+			if err := utils.RevokeSession(session); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+				return
+			}
+		}
+	}
+
+	if req.RefreshToken != "" {
+		if err := utils.RevokeRefreshToken(c.GetUint("user_id"), req.RefreshToken); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logout successful"})
+}
+
 // GetCurrentUser returns the authenticated user's information
 func GetCurrentUser(c *gin.Context) {
 	userID, _ := c.Get("user_id")
@@ -128,12 +317,18 @@ func GetCurrentUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"user": gin.H{
-			"id":    user.ID,
-			"nama":  user.Nama,
-			"email": user.Email,
-			"role":  user.Role,
-			"foto":  user.Foto,
+			"id":            user.ID,
+			"nama":          user.Nama,
+			"email":         user.Email,
+			"role":          user.Role,
+			"foto":          user.Foto,
+			"verified":      user.IsVerified(),
+			"has_password":  user.HasPassword(),
+			"pending_email": user.PendingEmail,
+			"delete_after":  user.DeleteAfter,
 		},
+		"permissions":     policy.Permissions(user.Role),
+		"impersonated_by": c.GetUint("impersonator_id"),
 	})
 }
 
@@ -220,63 +415,79 @@ func GoogleCallback(c *gin.Context) {
 
 	data, _ := io.ReadAll(resp.Body)
 	var googleUser struct {
-		Email   string `json:"email"`
-		Name    string `json:"name"`
-		Picture string `json:"picture"`
+		ID            string `json:"id"`
+		Email         string `json:"email"`
+		VerifiedEmail bool   `json:"verified_email"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
 	}
 	json.Unmarshal(data, &googleUser)
 
-	// Check if user exists
-	var user models.User
-	err = config.DB.Where("email = ?", googleUser.Email).First(&user).Error
-	
-	if err != nil {
-		// Create new user
-		user = models.User{
-			Nama:  googleUser.Name,
-			Email: googleUser.Email,
-			Role:  "user",
-			Foto:  googleUser.Picture,
-		}
-		
-		// Generate random password for OAuth users
-		randomPassword := generateRandomPassword()
-		user.Password = randomPassword
-		if err := user.HashPassword(); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
-			return
-		}
-		
-		if err := config.DB.Create(&user).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
-			return
-		}
+	if googleUser.ID == "" || googleUser.Email == "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user info"})
+		return
 	}
 
-	// Generate JWT
-	jwtToken, err := utils.GenerateToken(user.ID, user.Email, user.Role)
+	// Find the linked account, link by verified email or create a new user
+	user, err := resolveExternalUser(externalAccount{
+		Provider:      "google",
+		Subject:       googleUser.ID,
+		Email:         googleUser.Email,
+		EmailVerified: googleUser.VerifiedEmail,
+		Name:          googleUser.Name,
+		Picture:       googleUser.Picture,
+	})
+	if err == errIdentityConflict {
+		c.JSON(http.StatusConflict, gin.H{"error": identityConflictMessage})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 
-	// Redirect to frontend callback with token and user data
+	redirectAfterExternalLogin(c, user)
+}
+
+// getFrontendURL returns the base URL of the frontend for redirects and email links
+func getFrontendURL() string {
 	frontendURL := os.Getenv("FRONTEND_URL")
 	if frontendURL == "" {
 		frontendURL = "http://localhost:3000"
 	}
-	
-	redirectURL := fmt.Sprintf("%s/auth/google/callback?token=%s&userId=%d&email=%s&nama=%s&role=%s&foto=%s",
-		frontendURL,
-		jwtToken,
-		user.ID,
-		url.QueryEscape(user.Email),
-		url.QueryEscape(user.Nama),
-		user.Role,
-		url.QueryEscape(user.Foto),
-	)
-	
-	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
+	return frontendURL
+}
+
+// issueTokens starts a new session for the requesting device and returns
+// its access token and refresh token
+func issueTokens(c *gin.Context, user *models.User) (string, string, error) {
+	session, err := utils.StartSession(user.ID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		return "", "", err
+	}
+
+	recordAuditAs(c, user.ID, utils.AuditLogin, "user", user.ID, nil, gin.H{
+		"session_id": session.ID,
+		"device":     session.Device,
+		"via":        c.FullPath(),
+	})
+
+	return issueSessionTokens(user, session.ID)
+}
+
+// issueSessionTokens creates a new access token and refresh token pair for an existing session
+func issueSessionTokens(user *models.User, sessionID uint) (string, string, error) {
+	token, err := utils.GenerateToken(user, sessionID)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := utils.IssueRefreshToken(user.ID, sessionID)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, refreshToken, nil
 }
 
 // generateStateOauthCookie generates a random state string
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"bulan2-backend/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCheckCanLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	verified := time.Now()
+
+	tests := []struct {
+		name       string
+		user       models.User
+		policy     string
+		want       bool
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "verified", user: models.User{Status: "active", VerifiedAt: &verified}, policy: "block", want: true},
+		{name: "unverified under block", user: models.User{Status: "active"}, policy: "block",
+			wantStatus: http.StatusForbidden, wantCode: "email_unverified"},
+		{name: "unverified under read_only", user: models.User{Status: "active"}, policy: "read_only", want: true},
+		{name: "unverified with policy off", user: models.User{Status: "active"}, policy: "off", want: true},
+		{name: "suspended", user: models.User{Status: "suspended", VerifiedAt: &verified}, policy: "off",
+			wantStatus: http.StatusForbidden, wantCode: "account_suspended"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("EMAIL_VERIFICATION_POLICY", tt.policy)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			if got := checkCanLogin(c, &tt.user); got != tt.want {
+				t.Fatalf("checkCanLogin() = %v, want %v", got, tt.want)
+			}
+			if tt.want {
+				if w.Body.Len() != 0 {
+					t.Errorf("checkCanLogin() wrote %s", w.Body)
+				}
+				return
+			}
+
+			var body struct {
+				Code string `json:"code"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tt.wantStatus || body.Code != tt.wantCode {
+				t.Errorf("checkCanLogin() responded %d %q, want %d %q", w.Code, body.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"bulan2-backend/config"
 	"bulan2-backend/models"
+	"bulan2-backend/policy"
 	"bulan2-backend/utils"
 	"net/http"
 
@@ -78,7 +79,7 @@ func CreateComment(c *gin.Context) {
 // DeleteComment deletes a comment
 func DeleteComment(c *gin.Context) {
 	id := c.Param("id")
-	role, _ := c.Get("role")
+	role := c.GetString("role")
 	userID, _ := c.Get("user_id")
 
 	var comment models.Comment
@@ -87,8 +88,8 @@ func DeleteComment(c *gin.Context) {
 		return
 	}
 
-	// Check ownership (admin can delete any comment)
-	if role == "user" && comment.UserID != userID.(uint) {
+	// Check ownership (comment.delete.any can delete any comment)
+	if comment.UserID != userID.(uint) && !policy.Can(role, policy.CommentDeleteAny) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
 		return
 	}
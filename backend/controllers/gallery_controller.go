@@ -3,6 +3,7 @@ package controllers
 import (
 	"bulan2-backend/config"
 	"bulan2-backend/models"
+	"bulan2-backend/policy"
 	"bulan2-backend/utils"
 	"net/http"
 	"path/filepath"
@@ -10,9 +11,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// GetGallery returns gallery photos (filtered by user role)
+// GetGallery returns gallery photos (filtered by user permissions)
 func GetGallery(c *gin.Context) {
-	role, _ := c.Get("role")
+	role := c.GetString("role")
 	userID, _ := c.Get("user_id")
 
 	// Pagination params
@@ -31,8 +32,8 @@ func GetGallery(c *gin.Context) {
 	
 	query := config.DB.Model(&models.Gallery{})
 
-	// Users only see their own photos
-	if role == "user" {
+	// Users without gallery.read.any only see their own photos
+	if !policy.Can(role, policy.GalleryReadAny) {
 		query = query.Where("uploader = ?", userID)
 	}
 
@@ -116,7 +117,7 @@ func UploadPhotos(c *gin.Context) {
 // DeletePhoto deletes a photo
 func DeletePhoto(c *gin.Context) {
 	id := c.Param("id")
-	role, _ := c.Get("role")
+	role := c.GetString("role")
 	userID, _ := c.Get("user_id")
 
 	var photo models.Gallery
@@ -125,8 +126,8 @@ func DeletePhoto(c *gin.Context) {
 		return
 	}
 
-	// Check ownership (users can only delete their own photos)
-	if role == "user" && photo.Uploader != userID.(uint) {
+	// Check ownership (only gallery.delete.any may delete other people's photos)
+	if photo.Uploader != userID.(uint) && !policy.Can(role, policy.GalleryDeleteAny) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
 		return
 	}
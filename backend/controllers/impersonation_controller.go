@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/policy"
+	"bulan2-backend/utils"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StartImpersonation issues a short-lived token that lets the admin act as
+// another user. The token has no refresh token, shows up in the user's
+// session list and every request made with it is logged against the admin.
+func StartImpersonation(c *gin.Context) {
+	adminID, _ := c.Get("user_id")
+
+	var user models.User
+	if err := config.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.ID == adminID.(uint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot impersonate yourself"})
+		return
+	}
+	// Impersonating someone with the same power would be a way around the audit trail
+	if policy.Can(user.Role, policy.UsersImpersonate) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This user cannot be impersonated"})
+		return
+	}
+	if !user.IsActive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Suspended users cannot be impersonated"})
+		return
+	}
+
+	session, err := utils.StartImpersonationSession(user.ID, adminID.(uint), c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start impersonation"})
+		return
+	}
+
+	token, err := utils.GenerateImpersonationToken(&user, adminID.(uint), session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	log.Printf("Impersonation: admin %d started session %d as user %d", adminID, session.ID, user.ID)
+	recordAudit(c, utils.AuditUserImpersonate, "user", user.ID, nil, gin.H{"session_id": session.ID})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Impersonation started",
+		"token":           token,
+		"expires_in":      int(utils.ImpersonationTTL.Seconds()),
+		"impersonator_id": adminID,
+		"user": gin.H{
+			"id":       user.ID,
+			"nama":     user.Nama,
+			"email":    user.Email,
+			"role":     user.Role,
+			"foto":     user.Foto,
+			"verified": user.IsVerified(),
+		},
+	})
+}
+
+// EndImpersonation lets the admin stop acting as the user before the token
+// expires. It is called with the impersonation token itself, which is revoked
+// along with its session; the request is logged like any other made with it.
+func EndImpersonation(c *gin.Context) {
+	value, _ := c.Get("claims")
+	claims, ok := value.(*utils.Claims)
+	if !ok || claims.ImpersonatorID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Not impersonating anyone"})
+		return
+	}
+
+	if err := utils.RevokeAccessToken(claims); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end impersonation"})
+		return
+	}
+	if session, err := utils.GetActiveSession(claims.UserID, claims.SessionID); err == nil {
+		if err := utils.RevokeSession(session); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end impersonation"})
+			return
+		}
+	}
+
+	log.Printf("Impersonation: admin %d ended session %d as user %d", claims.ImpersonatorID, claims.SessionID, claims.UserID)
+	recordAudit(c, utils.AuditImpersonationEnd, "user", claims.UserID, nil, gin.H{"session_id": claims.SessionID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Impersonation ended"})
+}
+
+// GetImpersonationLogs lists requests made during impersonation sessions,
+// optionally filtered by admin or impersonated user
+func GetImpersonationLogs(c *gin.Context) {
+	// Pagination params
+	page := utils.ParseInt(c.DefaultQuery("page", "1"), 1)
+	limit := utils.ParseInt(c.DefaultQuery("limit", "50"), 50)
+	if limit > 200 {
+		limit = 200
+	}
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.ImpersonationLog{})
+	if adminID := c.Query("impersonator_id"); adminID != "" {
+		query = query.Where("impersonator_id = ?", adminID)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var logs []models.ImpersonationLog
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch impersonation logs"})
+		return
+	}
+
+	totalPages := (int(total) + limit - 1) / limit
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": logs,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": totalPages,
+		},
+	})
+}
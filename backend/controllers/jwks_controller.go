@@ -0,0 +1,15 @@
+package controllers
+
+import (
+	"bulan2-backend/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJWKS publishes the public keys that access tokens are signed with, so
+// other services can verify tokens without sharing a secret
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": utils.JWKS()})
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// magicLinkCookie ties a login link to the browser that asked for it
+const magicLinkCookie = "magic_link_binding"
+
+// magicLinkCookiePath limits the binding cookie to the magic link endpoints
+const magicLinkCookiePath = "/api/auth/magic-link"
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type MagicLinkLoginRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// magicLinkEnabled reports whether login links are offered; set
+// MAGIC_LINK_LOGIN=off to turn them off
+func magicLinkEnabled() bool {
+	return os.Getenv("MAGIC_LINK_LOGIN") != "off"
+}
+
+// magicLinkTTL returns how long a login link stays valid
+func magicLinkTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("MAGIC_LINK_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return 10 * time.Minute
+}
+
+// RequestMagicLink emails a single-use login link. The link only works in
+// the browser that requested it, which receives a binding cookie.
+func RequestMagicLink(c *gin.Context) {
+	if !magicLinkEnabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Login links are disabled"})
+		return
+	}
+
+	var req MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Same response whether or not the account exists to avoid email enumeration
+	response := gin.H{"message": "If the email is registered, a login link has been sent"}
+
+	var user models.User
+	if err := config.DB.Where("email = ?", req.Email).First(&user).Error; err != nil || !user.IsActive() {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	ttl := magicLinkTTL()
+	token, binding, ok, err := utils.IssueMagicLink(user.ID, ttl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create login link"})
+		return
+	}
+	if !ok {
+		// A link went out moments ago; don't send another one
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(magicLinkCookie, binding, int(ttl.Seconds()), magicLinkCookiePath, "", os.Getenv("APP_ENV") == "production", true)
+
+	link := fmt.Sprintf("%s/magic-link?token=%s", getFrontendURL(), url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nOpen the link below to log in:\n\n%s\n\nThe link expires in %s, works once and only in the browser where you asked for it. If you did not request this, you can ignore this email.\n",
+		user.Nama, link, ttl)
+
+	if err := config.Mail.Send(user.Email, "Your login link", body); err != nil {
+		log.Printf("Failed to send login link to user %d: %v", user.ID, err)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// MagicLinkLogin signs in with a login link opened in the browser that
+// requested it
+func MagicLinkLogin(c *gin.Context) {
+	if !magicLinkEnabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Login links are disabled"})
+		return
+	}
+
+	var req MagicLinkLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	binding, _ := c.Cookie(magicLinkCookie)
+	userID, err := utils.ConsumeMagicLink(req.Token, binding)
+	if err == utils.ErrInvalidMagicLink {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid or expired login link. Request a new one from this browser",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete login"})
+		return
+	}
+
+	// The binding is single-use too
+	c.SetCookie(magicLinkCookie, "", -1, magicLinkCookiePath, "", os.Getenv("APP_ENV") == "production", true)
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login link"})
+		return
+	}
+	if !user.IsActive() {
+		respondAccountSuspended(c)
+		return
+	}
+
+	// Opening the link proves the user owns the address
+	if !user.IsVerified() {
+		now := time.Now()
+		if err := config.DB.Model(&user).Update("verified_at", now).Error; err != nil {
+			log.Printf("Failed to mark user %d verified after login link: %v", user.ID, err)
+		} else {
+			user.VerifiedAt = &now
+		}
+	}
+
+	// Two-factor users finish logging in with a code
+	if purpose := mfaPurpose(&user); purpose != "" {
+		respondMFAChallenge(c, &user, purpose)
+		return
+	}
+
+	respondLoginSuccess(c, &user)
+}
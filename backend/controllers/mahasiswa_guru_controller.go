@@ -3,6 +3,8 @@ package controllers
 import (
 	"bulan2-backend/config"
 	"bulan2-backend/models"
+	"bulan2-backend/policy"
+	"bulan2-backend/utils"
 	"net/http"
 	"strconv"
 
@@ -12,9 +14,8 @@ import (
 // RequestGuru - Mahasiswa request guru
 func RequestGuru(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	role, _ := c.Get("role")
 
-	if role != "user" {
+	if !policy.Can(c.GetString("role"), policy.MentorRequest) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Hanya mahasiswa yang bisa request guru"})
 		return
 	}
@@ -30,7 +31,7 @@ func RequestGuru(c *gin.Context) {
 
 	// Check if guru exists and has role 'guru'
 	var guru models.User
-	if err := config.DB.Where("id = ? AND role = ?", request.GuruID, "guru").First(&guru).Error; err != nil {
+	if err := config.DB.Where("id = ? AND role = ?", request.GuruID, policy.RoleGuru).First(&guru).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Guru tidak ditemukan"})
 		return
 	}
@@ -83,9 +84,8 @@ func GetMyGuru(c *gin.Context) {
 // GetMahasiswaGuruRequests - Guru lihat pending requests
 func GetMahasiswaGuruRequests(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	role, _ := c.Get("role")
 
-	if role != "guru" {
+	if !policy.Can(c.GetString("role"), policy.MentorReview) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Hanya guru yang bisa akses endpoint ini"})
 		return
 	}
@@ -102,9 +102,8 @@ func GetMahasiswaGuruRequests(c *gin.Context) {
 // ApproveMahasiswaGuru - Guru approve request
 func ApproveMahasiswaGuru(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	role, _ := c.Get("role")
 
-	if role != "guru" {
+	if !policy.Can(c.GetString("role"), policy.MentorReview) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Hanya guru yang bisa akses endpoint ini"})
 		return
 	}
@@ -128,6 +127,9 @@ func ApproveMahasiswaGuru(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, utils.AuditMentorshipApprove, "mahasiswa_guru", mahasiswaGuru.ID,
+		gin.H{"status": "pending"}, gin.H{"status": mahasiswaGuru.Status, "mahasiswa_id": mahasiswaGuru.MahasiswaID})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Request berhasil di-approve",
 		"data":    mahasiswaGuru,
@@ -137,9 +139,8 @@ func ApproveMahasiswaGuru(c *gin.Context) {
 // RejectMahasiswaGuru - Guru reject request
 func RejectMahasiswaGuru(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	role, _ := c.Get("role")
 
-	if role != "guru" {
+	if !policy.Can(c.GetString("role"), policy.MentorReview) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Hanya guru yang bisa akses endpoint ini"})
 		return
 	}
@@ -163,6 +164,9 @@ func RejectMahasiswaGuru(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, utils.AuditMentorshipReject, "mahasiswa_guru", mahasiswaGuru.ID,
+		gin.H{"status": "pending"}, gin.H{"status": mahasiswaGuru.Status, "mahasiswa_id": mahasiswaGuru.MahasiswaID})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Request berhasil di-reject",
 		"data":    mahasiswaGuru,
@@ -172,9 +176,8 @@ func RejectMahasiswaGuru(c *gin.Context) {
 // GetGuruMahasiswa - Guru lihat list mahasiswa yang di-approve
 func GetGuruMahasiswa(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	role, _ := c.Get("role")
 
-	if role != "guru" {
+	if !policy.Can(c.GetString("role"), policy.MentorReview) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Hanya guru yang bisa akses endpoint ini"})
 		return
 	}
@@ -191,7 +194,7 @@ func GetGuruMahasiswa(c *gin.Context) {
 // GetAllGuru - Mahasiswa get list semua guru
 func GetAllGuru(c *gin.Context) {
 	var gurus []models.User
-	if err := config.DB.Where("role = ?", "guru").Find(&gurus).Error; err != nil {
+	if err := config.DB.Where("role = ?", policy.RoleGuru).Find(&gurus).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data"})
 		return
 	}
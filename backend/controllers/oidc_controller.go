@@ -0,0 +1,314 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/policy"
+	"bulan2-backend/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// oauthCallbackPath is the frontend page that finishes every external login
+const oauthCallbackPath = "/auth/google/callback"
+
+// oidcCookieMaxAge is how long the login round-trip to the provider may take
+const oidcCookieMaxAge = 600
+
+var errIdentityConflict = errors.New("email belongs to an account that is not linked to this provider")
+
+// errLinkRoleConflict is returned when a provider that manages roles would
+// take over an existing account with a role other than the default
+var errLinkRoleConflict = errors.New("email belongs to an account with a role the provider does not manage")
+
+// identityConflictMessage explains errIdentityConflict to the user
+const identityConflictMessage = "An account with this email already exists. Log in with your password first, or use a provider that verifies your email"
+
+// externalAccount is what an identity provider tells us about a user
+type externalAccount struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+	// DefaultRoleOnly links existing accounts only when they have the
+	// default role, for providers that go on to set the role themselves
+	DefaultRoleOnly bool
+}
+
+// resolveExternalUser finds the user linked to the provider account. An
+// unlinked account is linked to an existing user only when the provider has
+// verified the email; otherwise a new user is created.
+func resolveExternalUser(account externalAccount) (*models.User, error) {
+	var user models.User
+
+	var identity models.UserIdentity
+	err := config.DB.Where("provider = ? AND subject = ?", account.Provider, account.Subject).First(&identity).Error
+	if err == nil {
+		if err := config.DB.First(&user, identity.UserID).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("email = ?", account.Email).First(&user).Error
+		switch {
+		case err == nil:
+			// Linking on an unverified email would let anyone claim the account
+			if !account.EmailVerified {
+				return errIdentityConflict
+			}
+			if account.DefaultRoleOnly && user.Role != policy.RoleUser {
+				return errLinkRoleConflict
+			}
+			if !user.IsVerified() {
+				now := time.Now()
+				user.VerifiedAt = &now
+				if err := tx.Model(&user).Update("verified_at", now).Error; err != nil {
+					return err
+				}
+			}
+
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			user = models.User{
+				Nama:         account.Name,
+				Email:        account.Email,
+				Role:         policy.RoleUser,
+				Foto:         account.Picture,
+				Passwordless: true,
+			}
+			if user.Nama == "" {
+				user.Nama = account.Email
+			}
+			if account.EmailVerified {
+				now := time.Now()
+				user.VerifiedAt = &now
+			}
+
+			// Generate random password for OAuth users
+			user.Password = generateRandomPassword()
+			if err := user.HashPassword(); err != nil {
+				return err
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+
+		default:
+			return err
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:   user.ID,
+			Provider: account.Provider,
+			Subject:  account.Subject,
+			Email:    account.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// redirectAfterExternalLogin sends the browser back to the frontend with a
+// one-time code. Tokens are only handed out by ExchangeLoginCode, so they
+// never end up in browser history, proxy logs or the request log.
+func redirectAfterExternalLogin(c *gin.Context, user *models.User) {
+	code, err := utils.IssueLoginCode(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete login"})
+		return
+	}
+
+	redirectURL := fmt.Sprintf("%s%s?code=%s", getFrontendURL(), oauthCallbackPath, url.QueryEscape(code))
+	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
+}
+
+// ExchangeLoginCode trades a one-time code from an external login for
+// tokens, or for a partial token when a 2FA step is still needed
+func ExchangeLoginCode(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := utils.ConsumeLoginCode(req.Code)
+	if err == utils.ErrInvalidLoginCode {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login code"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete login"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login code"})
+		return
+	}
+	if !checkCanLogin(c, &user) {
+		return
+	}
+
+	// Two-factor users finish logging in with a code
+	if purpose := mfaPurpose(&user); purpose != "" {
+		respondMFAChallenge(c, &user, purpose)
+		return
+	}
+
+	respondLoginSuccess(c, &user)
+}
+
+// GetOIDCProviders lists the configured OpenID Connect providers
+func GetOIDCProviders(c *gin.Context) {
+	providers := make([]gin.H, 0, len(config.OIDCProviders))
+	for _, p := range config.OIDCProviders {
+		providers = append(providers, gin.H{
+			"name":         p.Name,
+			"display_name": p.DisplayName,
+			"login_url":    "/api/auth/oidc/" + p.Name + "/login",
+		})
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i]["name"].(string) < providers[j]["name"].(string)
+	})
+
+	c.JSON(http.StatusOK, gin.H{"data": providers})
+}
+
+// OIDCLogin starts an authorization code flow with PKCE at the provider
+func OIDCLogin(c *gin.Context) {
+	provider, ok := config.OIDCProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
+		return
+	}
+
+	if err := provider.Discover(c.Request.Context()); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Login provider is unavailable"})
+		return
+	}
+
+	state := generateStateOauthCookie()
+	nonce := utils.GenerateSecureToken(16)
+	verifier := oauth2.GenerateVerifier()
+
+	cookiePath := "/api/auth/oidc/" + provider.Name
+	c.SetCookie("oidc_state", state, oidcCookieMaxAge, cookiePath, "", false, true)
+	c.SetCookie("oidc_nonce", nonce, oidcCookieMaxAge, cookiePath, "", false, true)
+	c.SetCookie("oidc_verifier", verifier, oidcCookieMaxAge, cookiePath, "", false, true)
+
+	url := provider.OAuth2.AuthCodeURL(state,
+		oauth2.SetAuthURLParam("nonce", nonce),
+		oauth2.S256ChallengeOption(verifier),
+	)
+	c.Redirect(http.StatusTemporaryRedirect, url)
+}
+
+// OIDCCallback finishes the flow: it validates the ID token and signs the user in
+func OIDCCallback(c *gin.Context) {
+	provider, ok := config.OIDCProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
+		return
+	}
+
+	// Verify state
+	state, _ := c.Cookie("oidc_state")
+	if state == "" || state != c.Query("state") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid state parameter"})
+		return
+	}
+	nonce, _ := c.Cookie("oidc_nonce")
+	verifier, _ := c.Cookie("oidc_verifier")
+
+	// The round-trip cookies are single-use
+	cookiePath := "/api/auth/oidc/" + provider.Name
+	c.SetCookie("oidc_state", "", -1, cookiePath, "", false, true)
+	c.SetCookie("oidc_nonce", "", -1, cookiePath, "", false, true)
+	c.SetCookie("oidc_verifier", "", -1, cookiePath, "", false, true)
+
+	if errParam := c.Query("error"); errParam != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login was cancelled or denied by the provider"})
+		return
+	}
+
+	token, err := provider.OAuth2.Exchange(c.Request.Context(), c.Query("code"), oauth2.VerifierOption(verifier))
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to exchange token"})
+		return
+	}
+
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Provider did not return an ID token"})
+		return
+	}
+
+	claims, err := provider.VerifyIDToken(c.Request.Context(), rawIDToken, nonce)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ID token"})
+		return
+	}
+
+	if claims.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provider did not share an email address"})
+		return
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.PreferredUsername
+	}
+
+	user, err := resolveExternalUser(externalAccount{
+		Provider:      provider.Name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          name,
+		Picture:       claims.Picture,
+	})
+	if err == errIdentityConflict {
+		c.JSON(http.StatusConflict, gin.H{"error": identityConflictMessage})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	redirectAfterExternalLogin(c, user)
+}
+
+// GetMyIdentities lists the external accounts linked to the current user
+func GetMyIdentities(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var identities []models.UserIdentity
+	if err := config.DB.Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch linked accounts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": identities})
+}
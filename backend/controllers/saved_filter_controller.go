@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/utils"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSavedFiltersPerUser caps how many smart filters a user can keep
+const maxSavedFiltersPerUser = 50
+
+// SavedFilterInput is the body for creating and updating smart filters. On
+// update, fields that are left out keep their current value.
+type SavedFilterInput struct {
+	Name      *string `json:"name"`
+	Query     *string `json:"query"`
+	SortOrder *int    `json:"sort_order"`
+}
+
+func (in *SavedFilterInput) apply(filter *models.SavedFilter) error {
+	if in.Name != nil {
+		name := strings.TrimSpace(*in.Name)
+		if name == "" || len(name) > 100 {
+			return fmt.Errorf("name must be between 1 and 100 characters")
+		}
+		filter.Name = name
+	}
+	if in.Query != nil {
+		query := strings.TrimSpace(*in.Query)
+		terms, err := utils.ParseTodoQuery(query)
+		if err != nil {
+			return err
+		}
+		if len(terms) == 0 {
+			return fmt.Errorf("query is empty")
+		}
+		filter.Query = query
+	}
+	if in.SortOrder != nil {
+		filter.SortOrder = *in.SortOrder
+	}
+	return nil
+}
+
+// GetSavedFilters returns the user's smart filters. Run one with
+// GET /todos?filter_id=<id>.
+func GetSavedFilters(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var filters []models.SavedFilter
+	if err := config.DB.Where("user_id = ?", userID).
+		Order("sort_order ASC, id ASC").
+		Find(&filters).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch saved filters"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": filters})
+}
+
+// CreateSavedFilter saves a named smart filter for the current user
+func CreateSavedFilter(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var input SavedFilterInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Name == nil || input.Query == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name and query are required"})
+		return
+	}
+
+	var count int64
+	config.DB.Model(&models.SavedFilter{}).Where("user_id = ?", userID).Count(&count)
+	if count >= maxSavedFiltersPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("You can have at most %d saved filters", maxSavedFiltersPerUser)})
+		return
+	}
+
+	filter := models.SavedFilter{
+		UserID:    userID.(uint),
+		SortOrder: int(count),
+	}
+	if err := input.apply(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB.Create(&filter).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save filter"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Filter saved successfully",
+		"data":    filter,
+	})
+}
+
+// UpdateSavedFilter renames, edits or reorders a smart filter. Used for
+// both PUT and PATCH.
+func UpdateSavedFilter(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var filter models.SavedFilter
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&filter).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved filter not found"})
+		return
+	}
+
+	var input SavedFilterInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.apply(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB.Save(&filter).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update filter"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Filter updated successfully",
+		"data":    filter,
+	})
+}
+
+// DeleteSavedFilter deletes one of the user's smart filters
+func DeleteSavedFilter(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	result := config.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).Delete(&models.SavedFilter{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete filter"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved filter not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Filter deleted successfully"})
+}
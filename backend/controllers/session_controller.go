@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentSessionID returns the session the request was made from, or 0
+func currentSessionID(c *gin.Context) uint {
+	if claims, ok := c.Get("claims"); ok {
+		return claims.(*utils.Claims).SessionID
+	}
+	return 0
+}
+
+// GetSessions lists the current user's active sessions
+func GetSessions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentID := currentSessionID(c)
+
+	var sessions []models.Session
+	if err := utils.ActiveSessionsQuery(userID.(uint)).Order("last_seen_at DESC").Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	data := make([]gin.H, 0, len(sessions))
+	for _, s := range sessions {
+		data = append(data, gin.H{
+			"id":              s.ID,
+			"device":          s.Device,
+			"user_agent":      s.UserAgent,
+			"ip_address":      s.IPAddress,
+			"last_seen_at":    s.LastSeenAt,
+			"created_at":      s.CreatedAt,
+			"impersonator_id": s.ImpersonatorID,
+			"current":         s.ID == currentID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// RevokeSession signs one of the current user's sessions out
+func RevokeSession(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var session models.Session
+	if err := utils.ActiveSessionsQuery(userID.(uint)).First(&session, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if err := utils.RevokeSession(&session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+// RevokeOtherSessions signs out every session except the current one
+func RevokeOtherSessions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	count, err := utils.RevokeOtherSessions(userID.(uint), currentSessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Other sessions revoked successfully",
+		"count":   count,
+	})
+}
+
+// RevokeUserSessions lets an admin sign a user out of every session
+func RevokeUserSessions(c *gin.Context) {
+	var user models.User
+	if err := config.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := utils.RevokeUserTokens(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions revoked successfully"})
+}
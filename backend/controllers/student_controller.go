@@ -3,6 +3,7 @@ package controllers
 import (
 	"bulan2-backend/config"
 	"bulan2-backend/models"
+	"bulan2-backend/utils"
 	"net/http"
 	"strconv"
 
@@ -106,12 +107,20 @@ func UpdateStudent(c *gin.Context) {
 // DeleteStudent deletes a student
 func DeleteStudent(c *gin.Context) {
 	id := c.Param("id")
-	
-	if err := config.DB.Delete(&models.Siswa{}, id).Error; err != nil {
+
+	var student models.Siswa
+	if err := config.DB.First(&student, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Student not found"})
+		return
+	}
+
+	if err := config.DB.Delete(&student).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete student"})
 		return
 	}
 
+	recordAudit(c, utils.AuditStudentDelete, "siswa", student.ID, student, nil)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Student deleted successfully"})
 }
 
@@ -123,6 +132,8 @@ func ExportStudentsCSV(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, utils.AuditStudentExport, "siswa", 0, nil, gin.H{"rows": len(students)})
+
 	c.Header("Content-Type", "text/csv")
 	c.Header("Content-Disposition", "attachment; filename=students.csv")
 
@@ -0,0 +1,311 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/policy"
+	"bulan2-backend/utils"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxBatchTodos caps how many todos one batch can touch
+const maxBatchTodos = 500
+
+// maxBatchRecurring caps how many recurring todos one batch can complete.
+// Each one schedules its next occurrence while the batch holds its locks.
+const maxBatchRecurring = 50
+
+// Batch actions
+const (
+	batchComplete = "complete"
+	batchReopen   = "reopen"
+	batchDelete   = "delete"
+	batchMove     = "move"
+	batchTag      = "tag"
+)
+
+// Per-item batch results
+const (
+	batchResultOK        = "ok"
+	batchResultNotFound  = "not_found"
+	batchResultForbidden = "forbidden"
+	batchResultInvalid   = "invalid"
+)
+
+// BatchTodoRequest applies one action to todos picked by ids, or by a smart
+// filter given as query or filter_id. list_id is the target of move (null
+// for no list) and tag the tag to add.
+type BatchTodoRequest struct {
+	Action   string     `json:"action" binding:"required,oneof=complete reopen delete move tag"`
+	IDs      []uint     `json:"ids"`
+	Query    string     `json:"query"`
+	FilterID *uint      `json:"filter_id"`
+	ListID   optionalID `json:"list_id"`
+	Tag      string     `json:"tag"`
+	// Cascade makes complete and reopen apply to subtasks too
+	Cascade bool `json:"cascade"`
+}
+
+// BatchTodoResult is the outcome for one todo of a batch
+type BatchTodoResult struct {
+	ID     uint   `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// NextID is the occurrence created by completing a recurring todo
+	NextID *uint `json:"next_id,omitempty"`
+}
+
+// batchTodoIDs resolves the todos a batch applies to. A filter only ever
+// selects the user's own top-level todos.
+func batchTodoIDs(c *gin.Context, req *BatchTodoRequest) ([]uint, error) {
+	byFilter := req.Query != "" || req.FilterID != nil
+	if len(req.IDs) > 0 && byFilter {
+		return nil, fmt.Errorf("give either ids or a filter, not both")
+	}
+	if len(req.IDs) == 0 && !byFilter {
+		return nil, fmt.Errorf("ids or a filter is required")
+	}
+
+	if !byFilter {
+		if len(req.IDs) > maxBatchTodos {
+			return nil, fmt.Errorf("a batch can have at most %d todos", maxBatchTodos)
+		}
+		seen := make(map[uint]bool, len(req.IDs))
+		ids := make([]uint, 0, len(req.IDs))
+		for _, id := range req.IDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		return ids, nil
+	}
+
+	query := req.Query
+	if req.FilterID != nil {
+		var filter models.SavedFilter
+		if err := config.DB.Where("id = ? AND user_id = ?", *req.FilterID, c.GetUint("user_id")).First(&filter).Error; err != nil {
+			return nil, fmt.Errorf("saved filter not found")
+		}
+		query = filter.Query
+		if req.Query != "" {
+			query = filter.Query + " " + req.Query
+		}
+	}
+	terms, err := utils.ParseTodoQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint
+	err = applyTodoQuery(config.DB.Model(&models.Todo{}), terms).
+		Where("user_id = ? AND parent_id IS NULL", c.GetUint("user_id")).
+		Order("id ASC").
+		Limit(maxBatchTodos+1).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > maxBatchTodos {
+		return nil, fmt.Errorf("the filter matches more than %d todos; narrow it down", maxBatchTodos)
+	}
+	return ids, nil
+}
+
+// BatchTodos applies one action to many todos in a single transaction. Each
+// todo gets the same checks as the single-item handler; todos that fail them
+// are reported and skipped, while a database error rolls the whole batch
+// back.
+func BatchTodos(c *gin.Context) {
+	role := c.GetString("role")
+	userID := c.GetUint("user_id")
+
+	var req BatchTodoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check the action's arguments once, up front
+	var tagName string
+	switch req.Action {
+	case batchMove:
+		if !req.ListID.Set {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "list_id is required to move todos; use null for no list"})
+			return
+		}
+		input := TodoInput{ListID: req.ListID}
+		if err := input.checkReferences(userID); err != nil {
+			respondTodoReferenceError(c, err)
+			return
+		}
+	case batchTag:
+		names, err := normalizeTagNames([]string{req.Tag})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tagName = names[0]
+	}
+
+	ids, err := batchTodoIDs(c, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results := make([]BatchTodoResult, 0, len(ids))
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var todos []models.Todo
+		if len(ids) > 0 {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", ids).Find(&todos).Error; err != nil {
+				return err
+			}
+		}
+		byID := make(map[uint]*models.Todo, len(todos))
+		for i := range todos {
+			byID[todos[i].ID] = &todos[i]
+		}
+
+		var tag *models.Tag
+		tagCounts := map[uint]int{}
+		hasTag := map[uint]bool{}
+		if req.Action == batchTag {
+			tag = &models.Tag{UserID: userID, Name: tagName}
+			if err := tx.Where(tag).FirstOrCreate(tag).Error; err != nil {
+				return err
+			}
+			if len(ids) > 0 {
+				var counts []struct {
+					TodoID uint
+					Tags   int
+				}
+				if err := tx.Table("todo_tags").Select("todo_id, COUNT(*) AS tags").
+					Where("todo_id IN ?", ids).Group("todo_id").Scan(&counts).Error; err != nil {
+					return err
+				}
+				for _, count := range counts {
+					tagCounts[count.TodoID] = count.Tags
+				}
+				var tagged []uint
+				if err := tx.Table("todo_tags").Where("tag_id = ? AND todo_id IN ?", tag.ID, ids).
+					Pluck("todo_id", &tagged).Error; err != nil {
+					return err
+				}
+				for _, id := range tagged {
+					hasTag[id] = true
+				}
+			}
+		}
+
+		recurring := 0
+		for _, id := range ids {
+			result := BatchTodoResult{ID: id, Status: batchResultOK}
+			todo, ok := byID[id]
+
+			switch {
+			case !ok:
+				result.Status = batchResultNotFound
+			// Same rule as DeleteTodo: todo.delete.any can delete any todo,
+			// everything else is owner only
+			case todo.UserID != userID && !(req.Action == batchDelete && policy.Can(role, policy.TodoDeleteAny)):
+				result.Status = batchResultForbidden
+			case req.Action == batchMove && todo.ParentID != nil:
+				result.Status, result.Error = batchResultInvalid, "Subtasks stay in their parent's list"
+			case req.Action == batchTag && !hasTag[id] && tagCounts[id] >= maxTagsPerTodo:
+				result.Status, result.Error = batchResultInvalid, fmt.Sprintf("A todo can have at most %d tags", maxTagsPerTodo)
+			case req.Action == batchComplete && todo.SeriesID != nil && recurring >= maxBatchRecurring:
+				result.Status, result.Error = batchResultInvalid, fmt.Sprintf("A batch can complete at most %d recurring todos", maxBatchRecurring)
+			default:
+				if req.Action == batchComplete && todo.SeriesID != nil {
+					recurring++
+				}
+				next, err := applyBatchAction(tx, &req, todo, tag)
+				if err != nil {
+					return err
+				}
+				if next != nil {
+					result.NextID = &next.ID
+				}
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update todos"})
+		return
+	}
+
+	succeeded := 0
+	for _, r := range results {
+		if r.Status == batchResultOK {
+			succeeded++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   fmt.Sprintf("%d of %d todos updated", succeeded, len(results)),
+		"action":    req.Action,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"results":   results,
+	})
+}
+
+// applyBatchAction runs the batch action on one todo that passed the checks
+func applyBatchAction(tx *gorm.DB, req *BatchTodoRequest, todo *models.Todo, tag *models.Tag) (*models.Todo, error) {
+	switch req.Action {
+	case batchComplete:
+		return setTodoStatus(tx, todo, "done", req.Cascade)
+	case batchReopen:
+		return setTodoStatus(tx, todo, "pending", req.Cascade)
+	case batchDelete:
+		return nil, deleteTodo(tx, todo)
+	case batchMove:
+		return nil, moveTodoToList(tx, todo, req.ListID.Value)
+	case batchTag:
+		return nil, tx.Model(todo).Association("Tags").Append(tag)
+	}
+	return nil, nil
+}
+
+// moveTodoToList puts a top-level todo at the top of another list, taking
+// its subtasks and series along
+func moveTodoToList(tx *gorm.DB, todo *models.Todo, listID *uint) error {
+	if (todo.ListID == nil && listID == nil) || (todo.ListID != nil && listID != nil && *todo.ListID == *listID) {
+		return nil
+	}
+
+	position, err := topTodoPosition(tx, todo.UserID, listID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(todo).Updates(map[string]interface{}{
+		"list_id":  listID,
+		"position": position,
+	}).Error; err != nil {
+		return err
+	}
+	todo.ListID = listID
+	todo.Position = position
+	return followTodoToList(tx, todo)
+}
+
+// followTodoToList moves what belongs to a todo into the todo's list: its
+// subtasks and, for a recurring todo, its series, so later occurrences land
+// in the new list whichever occurrence was moved
+func followTodoToList(tx *gorm.DB, todo *models.Todo) error {
+	if err := tx.Model(&models.Todo{}).Where("parent_id = ?", todo.ID).Update("list_id", todo.ListID).Error; err != nil {
+		return err
+	}
+	if todo.SeriesID == nil {
+		return nil
+	}
+	return tx.Model(&models.TodoSeries{}).Where("id = ?", *todo.SeriesID).Update("list_id", todo.ListID).Error
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"bulan2-backend/models"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqlRecorder is a gorm logger keeping every statement, so a DryRun
+// session shows what a function would have sent to the database
+type sqlRecorder struct {
+	logger.Interface
+	statements []string
+}
+
+func (r *sqlRecorder) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	r.statements = append(r.statements, sql)
+}
+
+// ran reports whether a statement starting with prefix and containing
+// where was recorded
+func (r *sqlRecorder) ran(prefix, where string) bool {
+	for _, sql := range r.statements {
+		if strings.HasPrefix(sql, prefix) && strings.Contains(sql, where) {
+			return true
+		}
+	}
+	return false
+}
+
+func dryRunDB(t *testing.T) (*gorm.DB, *sqlRecorder) {
+	t.Helper()
+	rec := &sqlRecorder{Interface: logger.Discard}
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "test@tcp(127.0.0.1:0)/test", SkipInitializeWithVersion: true}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 rec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, rec
+}
+
+// Batch moves and UpdateTodo both follow a moved todo with followTodoToList
+func TestFollowTodoToList(t *testing.T) {
+	uintPtr := func(v uint) *uint { return &v }
+
+	tests := []struct {
+		name       string
+		seriesID   *uint
+		wantSeries bool
+	}{
+		{name: "recurring", seriesID: uintPtr(3), wantSeries: true},
+		{name: "one-off", seriesID: nil, wantSeries: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, rec := dryRunDB(t)
+			todo := &models.Todo{ID: 1, UserID: 1, ListID: uintPtr(2), SeriesID: tt.seriesID}
+
+			if err := followTodoToList(db, todo); err != nil {
+				t.Fatal(err)
+			}
+			if !rec.ran("UPDATE `todo` SET `list_id`=2", "WHERE parent_id = 1") {
+				t.Errorf("subtasks not moved, statements: %q", rec.statements)
+			}
+			if got := rec.ran("UPDATE `todo_series` SET `list_id`=2", "WHERE id = 3"); got != tt.wantSeries {
+				t.Errorf("series moved = %v, want %v, statements: %q", got, tt.wantSeries, rec.statements)
+			}
+		})
+	}
+}
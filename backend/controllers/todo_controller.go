@@ -3,15 +3,297 @@ package controllers
 import (
 	"bulan2-backend/config"
 	"bulan2-backend/models"
+	"bulan2-backend/policy"
 	"bulan2-backend/utils"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// maxTodoDescription caps the markdown description of a todo
+const maxTodoDescription = 10000
+
+// todoSorts maps the sort query values to ORDER BY clauses. Todos without
+// a due date always come last.
+var todoSorts = map[string]string{
+	"due_date":    "due_date IS NULL, due_date ASC",
+	"-due_date":   "due_date IS NULL, due_date DESC",
+	"priority":    "priority ASC",
+	"-priority":   "priority DESC",
+	"status":      "status ASC",
+	"-status":     "status DESC",
+	"created_at":  "created_at ASC",
+	"-created_at": "created_at DESC",
+	"updated_at":  "updated_at ASC",
+	"-updated_at": "updated_at DESC",
+	// The manual order is per list, so sort by list_id first when mixing lists
+	"position":  "list_id ASC, position ASC",
+	"-position": "list_id ASC, position DESC",
+}
+
+// optionalTime tells a missing JSON field apart from an explicit null, so
+// PATCH can clear a date without touching the ones that were left out
+type optionalTime struct {
+	Set   bool
+	Value *time.Time
+}
+
+func (o *optionalTime) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	t, err := parseDueDate(s)
+	if err != nil {
+		return err
+	}
+	o.Value = &t
+	return nil
+}
+
+// optionalID is optionalTime for references such as list_id
+type optionalID struct {
+	Set   bool
+	Value *uint
+}
+
+func (o *optionalID) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
+
+// parseDueDate accepts RFC 3339 timestamps, or a plain date meaning the end
+// of that day in APP_TIMEZONE, the zone smart filters read days in
+func parseDueDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseInLocation("2006-01-02", s, config.DefaultLocation())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, use RFC 3339 or YYYY-MM-DD", s)
+	}
+	return time.Date(d.Year(), d.Month(), d.Day(), 23, 59, 59, 0, d.Location()), nil
+}
+
+// TodoInput is the body for creating and updating todos. On update, fields
+// that are left out keep their current value.
+type TodoInput struct {
+	Title       *string      `json:"title"`
+	Description *string      `json:"description"`
+	Status      *string      `json:"status"`
+	Priority    *string      `json:"priority"`
+	DueDate     optionalTime `json:"due_date"`
+	CompletedAt optionalTime `json:"completed_at"`
+	ListID      optionalID   `json:"list_id"`
+	// Recurrence and Tags are handled by the handlers, not apply, because
+	// they live in other tables; Tags replaces the todo's tags
+	Recurrence optionalRecurrence `json:"recurrence"`
+	Tags       *[]string          `json:"tags"`
+}
+
+// checkReferences makes sure the todo only points at the user's own list
+func (in *TodoInput) checkReferences(userID uint) error {
+	if in.ListID.Set && in.ListID.Value != nil {
+		if _, err := findOwnTodoList(userID, *in.ListID.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply validates the input and copies it onto the todo, keeping Status and
+// CompletedAt consistent with each other
+func (in *TodoInput) apply(todo *models.Todo) error {
+	if in.Title != nil {
+		title := strings.TrimSpace(*in.Title)
+		if title == "" || len(title) > 255 {
+			return fmt.Errorf("title must be between 1 and 255 characters")
+		}
+		todo.Title = title
+	}
+	if in.Description != nil {
+		if len(*in.Description) > maxTodoDescription {
+			return fmt.Errorf("description must be at most %d characters", maxTodoDescription)
+		}
+		todo.Description = *in.Description
+	}
+	if in.Priority != nil {
+		switch *in.Priority {
+		case models.TodoPriorityLow, models.TodoPriorityMedium, models.TodoPriorityHigh:
+			todo.Priority = *in.Priority
+		default:
+			return fmt.Errorf("priority must be low, medium or high")
+		}
+	}
+	if in.DueDate.Set {
+		todo.DueDate = in.DueDate.Value
+	}
+	if in.ListID.Set {
+		todo.ListID = in.ListID.Value
+	}
+
+	if in.Status != nil {
+		switch *in.Status {
+		case "pending":
+			todo.Status = "pending"
+			todo.CompletedAt = nil
+		case "done":
+			if todo.Status != "done" || todo.CompletedAt == nil {
+				now := time.Now()
+				todo.CompletedAt = &now
+			}
+			todo.Status = "done"
+		default:
+			return fmt.Errorf("status must be pending or done")
+		}
+	}
+	// An explicit completion time wins and implies the status
+	if in.CompletedAt.Set {
+		todo.CompletedAt = in.CompletedAt.Value
+		if todo.CompletedAt != nil {
+			todo.Status = "done"
+		} else {
+			todo.Status = "pending"
+		}
+	}
+
+	return nil
+}
+
+// respondTodoReferenceError answers a failed checkReferences
+func respondTodoReferenceError(c *gin.Context, err error) {
+	if err == errTodoListNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Todo list not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check todo list"})
+}
+
+// applyTodoFilters narrows a todo query by the filter query parameters.
+// They all combine, including a smart filter given as query=<syntax> or
+// filter_id=<saved filter>.
+func applyTodoFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	// Subtasks are listed under their parent unless asked for; parent_id=any
+	// includes them and parent_id=<id> lists one todo's subtasks
+	switch parentID := c.Query("parent_id"); parentID {
+	case "":
+		query = query.Where("parent_id IS NULL")
+	case "any":
+	default:
+		query = query.Where("parent_id = ?", parentID)
+	}
+	// list_id=none selects todos that are in no list
+	switch listID := c.Query("list_id"); listID {
+	case "":
+	case "none":
+		query = query.Where("list_id IS NULL")
+	default:
+		query = query.Where("list_id = ?", listID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if priority := c.Query("priority"); priority != "" {
+		query = query.Where("priority IN ?", strings.Split(priority, ","))
+	}
+	switch c.Query("overdue") {
+	case "true":
+		query = query.Where("status = ? AND due_date < ?", "pending", time.Now())
+	case "false":
+		query = query.Where("NOT (status = ? AND due_date IS NOT NULL AND due_date < ?)", "pending", time.Now())
+	}
+	switch c.Query("has_due_date") {
+	case "true":
+		query = query.Where("due_date IS NOT NULL")
+	case "false":
+		query = query.Where("due_date IS NULL")
+	}
+	if after := c.Query("due_after"); after != "" {
+		t, err := parseTimeParam(after)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("due_date >= ?", t)
+	}
+	if before := c.Query("due_before"); before != "" {
+		t, err := parseTimeParam(before)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("due_date < ?", t)
+	}
+
+	// The remaining filters share the smart filter syntax: tag=a,b needs
+	// every tag, due=this-week is a due window and search is free text
+	var terms []utils.TodoQueryTerm
+	if tags := c.Query("tag"); tags != "" {
+		for _, tag := range strings.Split(tags, ",") {
+			terms = append(terms, utils.TodoQueryTerm{Key: utils.TodoQueryTag, Value: utils.NormalizeTagName(tag)})
+		}
+	}
+	if due := c.Query("due"); due != "" {
+		if !utils.TodoDueWindows[due] {
+			return nil, fmt.Errorf("invalid due window %q", due)
+		}
+		terms = append(terms, utils.TodoQueryTerm{Key: utils.TodoQueryDue, Value: due})
+	}
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		terms = append(terms, utils.TodoQueryTerm{Key: utils.TodoQueryText, Value: search})
+	}
+	if q := c.Query("query"); q != "" {
+		parsed, err := utils.ParseTodoQuery(q)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, parsed...)
+	}
+	if filterID := c.Query("filter_id"); filterID != "" {
+		var filter models.SavedFilter
+		if err := config.DB.Where("id = ? AND user_id = ?", filterID, c.GetUint("user_id")).First(&filter).Error; err != nil {
+			return nil, fmt.Errorf("saved filter not found")
+		}
+		parsed, err := utils.ParseTodoQuery(filter.Query)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, parsed...)
+	}
+	return applyTodoQuery(query, terms), nil
+}
+
+// todoOrder turns a comma-separated sort parameter into an ORDER BY clause
+func todoOrder(sort string) (string, error) {
+	if sort == "" {
+		return "created_at DESC", nil
+	}
+
+	var clauses []string
+	for _, key := range strings.Split(sort, ",") {
+		clause, ok := todoSorts[strings.TrimSpace(key)]
+		if !ok {
+			return "", fmt.Errorf("invalid sort %q", key)
+		}
+		clauses = append(clauses, clause)
+	}
+	// Keep pages stable when the sort keys tie
+	clauses = append(clauses, "id DESC")
+	return strings.Join(clauses, ", "), nil
+}
+
 // GetTodos returns user's todos or all todos (for admin)
 func GetTodos(c *gin.Context) {
-	role, _ := c.Get("role")
+	role := c.GetString("role")
 	userID, _ := c.Get("user_id")
 
 	// Pagination params
@@ -24,19 +306,31 @@ func GetTodos(c *gin.Context) {
 
 	var todos []models.Todo
 	var total int64
-	
-	query := config.DB.Model(&models.Todo{}).Preload("User")
 
-	// Regular users only see their own todos
-	if role == "user" {
+	query := config.DB.Model(&models.Todo{}).Preload("User").Preload("Series").Preload("Tags")
+
+	// Users without todo.read.any only see their own todos
+	if !policy.Can(role, policy.TodoReadAny) {
 		query = query.Where("user_id = ?", userID)
 	}
 
+	query, err := applyTodoFilters(c, query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := todoOrder(c.Query("sort"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Count total
 	query.Count(&total)
 
 	// Get paginated results
-	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&todos).Error; err != nil {
+	if err := query.Scopes(models.WithSubtaskCounts).Order(order).Offset(offset).Limit(limit).Find(&todos).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todos"})
 		return
 	}
@@ -58,25 +352,70 @@ func GetTodos(c *gin.Context) {
 func CreateTodo(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
-	var input struct {
-		Title string `json:"title" binding:"required"`
-	}
-
+	var input TodoInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if input.Title == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title is required"})
+		return
+	}
+
+	if err := input.checkReferences(userID.(uint)); err != nil {
+		respondTodoReferenceError(c, err)
+		return
+	}
 
 	todo := models.Todo{
-		UserID: userID.(uint),
-		Title:  input.Title,
-		Status: "pending",
+		UserID:   userID.(uint),
+		Status:   "pending",
+		Priority: models.TodoPriorityMedium,
+	}
+	if err := input.apply(&todo); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	if err := config.DB.Create(&todo).Error; err != nil {
+	rec, err := parseRecurrence(input.Recurrence)
+	if err == nil && rec != nil {
+		err = checkRecurring(&todo, rec)
+	}
+	var tags []string
+	if err == nil && input.Tags != nil {
+		tags, err = normalizeTagNames(*input.Tags)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var series *models.TodoSeries
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if rec != nil {
+			var err error
+			if series, err = startSeries(tx, &todo, rec); err != nil {
+				return err
+			}
+		}
+		// New todos go to the top of their list
+		var err error
+		if todo.Position, err = topTodoPosition(tx, todo.UserID, todo.ListID); err != nil {
+			return err
+		}
+		if err := tx.Create(&todo).Error; err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			return setTodoTags(tx, &todo, tags)
+		}
+		return nil
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create todo"})
 		return
 	}
+	todo.Series = series
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Todo created successfully",
@@ -84,7 +423,172 @@ func CreateTodo(c *gin.Context) {
 	})
 }
 
-// ToggleTodoStatus toggles todo status between pending and done
+// UpdateTodo edits a todo. Used for both PUT and PATCH; fields left out of
+// the body are unchanged and null clears a date. On a recurring todo,
+// scope=all also changes the series and its other pending occurrences, and
+// completing it creates the next occurrence.
+func UpdateTodo(c *gin.Context) {
+	id := c.Param("id")
+	userID, _ := c.Get("user_id")
+
+	var todo models.Todo
+	if err := config.DB.First(&todo, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+		return
+	}
+
+	// Check ownership
+	if todo.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	scope, err := todoScope(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input TodoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.checkReferences(userID.(uint)); err != nil {
+		respondTodoReferenceError(c, err)
+		return
+	}
+	if input.ListID.Set && todo.ParentID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Subtasks stay in their parent's list"})
+		return
+	}
+	wasDone := todo.Status == "done"
+	oldListID := todo.ListID
+	if err := input.apply(&todo); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rec, err := parseRecurrence(input.Recurrence)
+	if err == nil && rec != nil {
+		err = checkRecurring(&todo, rec)
+	}
+	var tags []string
+	if err == nil && input.Tags != nil {
+		tags, err = normalizeTagNames(*input.Tags)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	listChanged := (oldListID == nil) != (todo.ListID == nil) ||
+		(oldListID != nil && *oldListID != *todo.ListID)
+
+	var next *models.Todo
+	var series *models.TodoSeries
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if input.Recurrence.Set {
+			var err error
+			if series, err = setRecurrence(tx, &todo, rec); err != nil {
+				return err
+			}
+		}
+		if scope == todoScopeAll && todo.SeriesID != nil {
+			if err := updateSeriesTemplate(tx, &todo, &input); err != nil {
+				return err
+			}
+		}
+		// A todo moved to another list goes to the top of it
+		if listChanged && todo.ParentID == nil {
+			var err error
+			if todo.Position, err = topTodoPosition(tx, todo.UserID, todo.ListID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(&todo).Error; err != nil {
+			return err
+		}
+		if input.Tags != nil {
+			if err := setTodoTags(tx, &todo, tags); err != nil {
+				return err
+			}
+		}
+		// Subtasks and the series move along with the todo
+		if listChanged && todo.ParentID == nil {
+			if err := followTodoToList(tx, &todo); err != nil {
+				return err
+			}
+		}
+		if !wasDone && todo.Status == "done" {
+			var err error
+			next, err = advanceSeries(tx, &todo, time.Now())
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update todo"})
+		return
+	}
+	if series != nil {
+		todo.Series = series
+	}
+
+	response := gin.H{
+		"message": "Todo updated successfully",
+		"data":    todo,
+	}
+	if next != nil {
+		response["next"] = next
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// setTodoStatus marks a todo done or pending and saves it. With cascade its
+// subtasks follow. Completing a recurring todo returns its next occurrence.
+func setTodoStatus(tx *gorm.DB, todo *models.Todo, status string, cascade bool) (*models.Todo, error) {
+	if status == "done" {
+		if todo.Status != "done" || todo.CompletedAt == nil {
+			now := time.Now()
+			todo.CompletedAt = &now
+		}
+	} else {
+		todo.CompletedAt = nil
+	}
+	todo.Status = status
+
+	if err := tx.Save(todo).Error; err != nil {
+		return nil, err
+	}
+	if cascade {
+		// Subtasks already in the new state keep their completion time
+		if err := tx.Model(&models.Todo{}).
+			Where("parent_id = ? AND status <> ?", todo.ID, todo.Status).
+			Updates(map[string]interface{}{
+				"status":       todo.Status,
+				"completed_at": todo.CompletedAt,
+			}).Error; err != nil {
+			return nil, err
+		}
+	}
+	if todo.Status == "done" {
+		return advanceSeries(tx, todo, time.Now())
+	}
+	return nil, nil
+}
+
+// deleteTodo soft-deletes a todo together with its subtasks
+func deleteTodo(tx *gorm.DB, todo *models.Todo) error {
+	if err := tx.Where("parent_id = ?", todo.ID).Delete(&models.Todo{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(todo).Error
+}
+
+// ToggleTodoStatus toggles todo status between pending and done. With
+// cascade=true the todo's subtasks get the same status. Completing a
+// recurring todo creates its next occurrence.
 func ToggleTodoStatus(c *gin.Context) {
 	id := c.Param("id")
 	userID, _ := c.Get("user_id")
@@ -102,27 +606,38 @@ func ToggleTodoStatus(c *gin.Context) {
 	}
 
 	// Toggle status
-	if todo.Status == "pending" {
-		todo.Status = "done"
-	} else {
-		todo.Status = "pending"
+	status := "done"
+	if todo.Status == "done" {
+		status = "pending"
 	}
 
-	if err := config.DB.Save(&todo).Error; err != nil {
+	cascade := c.Query("cascade") == "true"
+	var next *models.Todo
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		next, err = setTodoStatus(tx, &todo, status, cascade)
+		return err
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update todo"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"message": "Todo status updated successfully",
 		"data":    todo,
-	})
+	}
+	if next != nil {
+		response["next"] = next
+	}
+	c.JSON(http.StatusOK, response)
 }
 
-// DeleteTodo deletes a todo
+// DeleteTodo deletes a todo. For a recurring todo, scope=all also deletes
+// the series and its pending occurrences.
 func DeleteTodo(c *gin.Context) {
 	id := c.Param("id")
-	role, _ := c.Get("role")
+	role := c.GetString("role")
 	userID, _ := c.Get("user_id")
 
 	var todo models.Todo
@@ -131,13 +646,35 @@ func DeleteTodo(c *gin.Context) {
 		return
 	}
 
-	// Check ownership (admin can delete any todo)
-	if role == "user" && todo.UserID != userID.(uint) {
+	// Check ownership (todo.delete.any can delete any todo)
+	if todo.UserID != userID.(uint) && !policy.Can(role, policy.TodoDeleteAny) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
 		return
 	}
 
-	if err := config.DB.Delete(&todo).Error; err != nil {
+	scope, err := todoScope(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if scope == todoScopeAll && todo.SeriesID != nil {
+			pending := tx.Model(&models.Todo{}).Select("id").
+				Where("series_id = ? AND status = ?", *todo.SeriesID, "pending")
+			if err := tx.Where("parent_id IN (?)", pending).Delete(&models.Todo{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("series_id = ? AND status = ?", *todo.SeriesID, "pending").Delete(&models.Todo{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&models.TodoSeries{}, *todo.SeriesID).Error; err != nil {
+				return err
+			}
+		}
+		return deleteTodo(tx, &todo)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete todo"})
 		return
 	}
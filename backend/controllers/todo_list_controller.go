@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxTodoListsPerUser caps how many lists, archived ones included, a user can keep
+const maxTodoListsPerUser = 100
+
+var todoListColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+var errTodoListNotFound = errors.New("todo list not found")
+
+// TodoListInput is the body for creating and updating lists. On update,
+// fields that are left out keep their current value.
+type TodoListInput struct {
+	Name      *string `json:"name"`
+	Color     *string `json:"color"`
+	Archived  *bool   `json:"archived"`
+	SortOrder *int    `json:"sort_order"`
+}
+
+func (in *TodoListInput) apply(list *models.TodoList) error {
+	if in.Name != nil {
+		name := strings.TrimSpace(*in.Name)
+		if name == "" || len(name) > 100 {
+			return fmt.Errorf("name must be between 1 and 100 characters")
+		}
+		list.Name = name
+	}
+	if in.Color != nil {
+		if !todoListColorPattern.MatchString(*in.Color) {
+			return fmt.Errorf("color must be a hex color like #4e73df")
+		}
+		list.Color = strings.ToLower(*in.Color)
+	}
+	if in.Archived != nil {
+		list.Archived = *in.Archived
+	}
+	if in.SortOrder != nil {
+		list.SortOrder = *in.SortOrder
+	}
+	return nil
+}
+
+// findOwnTodoList loads one of the user's lists, with todo counts
+func findOwnTodoList(userID uint, id interface{}) (*models.TodoList, error) {
+	var list models.TodoList
+	err := config.DB.Scopes(models.WithTodoCounts).
+		Where("todo_lists.id = ? AND todo_lists.user_id = ?", id, userID).
+		First(&list).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errTodoListNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetTodoLists returns the user's lists with pending and done counts.
+// Archived lists are left out unless archived=true or archived=all.
+func GetTodoLists(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	query := config.DB.Model(&models.TodoList{}).Scopes(models.WithTodoCounts).
+		Where("todo_lists.user_id = ?", userID)
+
+	switch c.DefaultQuery("archived", "false") {
+	case "false":
+		query = query.Where("todo_lists.archived = ?", false)
+	case "true":
+		query = query.Where("todo_lists.archived = ?", true)
+	}
+
+	var lists []models.TodoList
+	if err := query.Order("todo_lists.sort_order ASC, todo_lists.id ASC").Find(&lists).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todo lists"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": lists})
+}
+
+// GetTodoList returns one of the user's lists with its counts
+func GetTodoList(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	list, err := findOwnTodoList(userID.(uint), c.Param("id"))
+	if err == errTodoListNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Todo list not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todo list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// CreateTodoList creates a list for the current user
+func CreateTodoList(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var input TodoListInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Name == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	var count int64
+	config.DB.Model(&models.TodoList{}).Where("user_id = ?", userID).Count(&count)
+	if count >= maxTodoListsPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("You can have at most %d lists", maxTodoListsPerUser)})
+		return
+	}
+
+	list := models.TodoList{
+		UserID:    userID.(uint),
+		Color:     "#4e73df",
+		SortOrder: int(count),
+	}
+	if err := input.apply(&list); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB.Create(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create todo list"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Todo list created successfully",
+		"data":    list,
+	})
+}
+
+// UpdateTodoList renames, recolors, archives or reorders a list. Used for
+// both PUT and PATCH.
+func UpdateTodoList(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	list, err := findOwnTodoList(userID.(uint), c.Param("id"))
+	if err == errTodoListNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Todo list not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todo list"})
+		return
+	}
+
+	var input TodoListInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.apply(list); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB.Save(list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update todo list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Todo list updated successfully",
+		"data":    list,
+	})
+}
+
+// DeleteTodoList deletes a list. Its todos move back to the unlisted inbox,
+// or are deleted with it when delete_todos=true.
+func DeleteTodoList(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	list, err := findOwnTodoList(userID.(uint), c.Param("id"))
+	if err == errTodoListNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Todo list not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todo list"})
+		return
+	}
+
+	deleteTodos := c.Query("delete_todos") == "true"
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Recurring todos keep repeating, outside any list
+		if err := tx.Model(&models.TodoSeries{}).Where("list_id = ?", list.ID).Update("list_id", nil).Error; err != nil {
+			return err
+		}
+		todos := tx.Model(&models.Todo{}).Where("list_id = ?", list.ID)
+		if deleteTodos {
+			if err := todos.Delete(&models.Todo{}).Error; err != nil {
+				return err
+			}
+		} else if err := todos.Update("list_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.TodoList{}, list.ID).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete todo list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Todo list deleted successfully"})
+}
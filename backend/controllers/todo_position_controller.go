@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/utils"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errTodoOrderChanged means the neighbours a move was based on are gone or
+// no longer in that order, usually because another tab moved them first
+var errTodoOrderChanged = errors.New("todo order changed")
+
+var errSubtaskPosition = errors.New("subtasks are ordered through their parent")
+
+// errPermissionDenied lets transactions bail out on someone else's todo
+var errPermissionDenied = errors.New("permission denied")
+
+// isLockConflict reports whether err is MySQL giving up on a row lock, a
+// deadlock (1213) or a lock wait timeout (1205), which for concurrent
+// reorders means another one won
+func isLockConflict(err error) bool {
+	var myErr *mysql.MySQLError
+	return errors.As(err, &myErr) && (myErr.Number == 1213 || myErr.Number == 1205)
+}
+
+// MoveTodoRequest places a todo after one todo and/or before another, as
+// seen in the client's list. Leaving both out moves it to the top.
+type MoveTodoRequest struct {
+	AfterID  *uint `json:"after_id"`
+	BeforeID *uint `json:"before_id"`
+}
+
+// todoPositionScope selects the todos that share a manual order with todo:
+// the same user's top-level todos in the same list
+func todoPositionScope(tx *gorm.DB, userID uint, listID *uint) *gorm.DB {
+	query := tx.Model(&models.Todo{}).Where("user_id = ? AND parent_id IS NULL", userID)
+	if listID == nil {
+		return query.Where("list_id IS NULL")
+	}
+	return query.Where("list_id = ?", *listID)
+}
+
+// topTodoPosition returns a rank above every todo in the list
+func topTodoPosition(tx *gorm.DB, userID uint, listID *uint) (string, error) {
+	var first string
+	err := todoPositionScope(tx, userID, listID).
+		Where("position <> ''").
+		Select("COALESCE(MIN(position), '')").
+		Scan(&first).Error
+	if err != nil {
+		return "", err
+	}
+	return utils.RankBetween("", first)
+}
+
+// rebalanceTodoPositions gives every todo in the list a fresh, evenly
+// spaced rank in its current order. Todos without a rank come first, newest
+// first, as they did before manual ordering existed.
+func rebalanceTodoPositions(tx *gorm.DB, userID uint, listID *uint) error {
+	var ids []uint
+	err := todoPositionScope(tx, userID, listID).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Order("position ASC, created_at DESC, id DESC").
+		Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+
+	for i, rank := range utils.SpreadRanks(len(ids)) {
+		if err := tx.Model(&models.Todo{}).Where("id = ?", ids[i]).Update("position", rank).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveTodo ranks todo between its new neighbours. The rows involved, and
+// the gap the todo lands in, are locked until the transaction ends, so
+// concurrent moves into the same place queue up instead of picking the same
+// rank. Moves based on a stale view fail with errTodoOrderChanged.
+func moveTodo(tx *gorm.DB, todo *models.Todo, afterID, beforeID *uint) error {
+	if todo.ParentID != nil {
+		return errSubtaskPosition
+	}
+
+	// Ranks left empty by AutoMigrate or shared after a list was deleted
+	// make "between" ambiguous, so spread the list out first
+	var ambiguous int64
+	err := todoPositionScope(tx, todo.UserID, todo.ListID).
+		Select("COUNT(*) - COUNT(DISTINCT NULLIF(position, ''))").
+		Scan(&ambiguous).Error
+	if err != nil {
+		return err
+	}
+	if ambiguous > 0 {
+		if err := rebalanceTodoPositions(tx, todo.UserID, todo.ListID); err != nil {
+			return err
+		}
+	}
+
+	rank, err := rankForMove(tx, todo, afterID, beforeID)
+	if err != nil {
+		return err
+	}
+	if len(rank) > utils.MaxRankLength {
+		// Too many moves into the same spot; spread out and try once more
+		if err := rebalanceTodoPositions(tx, todo.UserID, todo.ListID); err != nil {
+			return err
+		}
+		if rank, err = rankForMove(tx, todo, afterID, beforeID); err != nil {
+			return err
+		}
+	}
+
+	todo.Position = rank
+	return tx.Model(todo).Update("position", rank).Error
+}
+
+// rankForMove picks a rank just after the after todo, or just before the
+// before todo when only that one is given. Ranking against the actual
+// neighbour rather than the other given todo keeps moves correct in
+// filtered views, where the two may not be adjacent.
+func rankForMove(tx *gorm.DB, todo *models.Todo, afterID, beforeID *uint) (string, error) {
+	neighbour := func(id uint) (string, error) {
+		if id == todo.ID {
+			return "", errTodoOrderChanged
+		}
+		var n models.Todo
+		err := todoPositionScope(tx, todo.UserID, todo.ListID).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", id).
+			First(&n).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", errTodoOrderChanged
+		}
+		return n.Position, err
+	}
+	// others locks and reads the closest rank on one side, skipping todo
+	others := func(where, order string, pos string) (string, error) {
+		var ranks []string
+		err := todoPositionScope(tx, todo.UserID, todo.ListID).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id <> ?", todo.ID).
+			Where(where, pos).
+			Order(order).
+			Limit(1).
+			Pluck("position", &ranks).Error
+		if err != nil || len(ranks) == 0 {
+			return "", err
+		}
+		return ranks[0], nil
+	}
+
+	var lo, hi string
+	var err error
+	if afterID != nil {
+		if lo, err = neighbour(*afterID); err != nil {
+			return "", err
+		}
+	}
+	if beforeID != nil {
+		if hi, err = neighbour(*beforeID); err != nil {
+			return "", err
+		}
+		if afterID != nil && lo >= hi {
+			return "", errTodoOrderChanged
+		}
+	}
+
+	switch {
+	case afterID != nil:
+		hi, err = others("position > ?", "position ASC", lo)
+	case beforeID != nil:
+		lo, err = others("position < ?", "position DESC", hi)
+	default:
+		hi, err = others("position > ?", "position ASC", "")
+	}
+	if err != nil {
+		return "", err
+	}
+	return utils.RankBetween(lo, hi)
+}
+
+// MoveTodo moves a todo in the user's manual order, between the todos it
+// was dropped between. Only the moved todo gets a new rank. Returns 409 when
+// the list changed since the client loaded it.
+func MoveTodo(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req MoveTodoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var todo models.Todo
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&todo, c.Param("id")).Error; err != nil {
+			return err
+		}
+		// Check ownership
+		if todo.UserID != userID.(uint) {
+			return errPermissionDenied
+		}
+		return moveTodo(tx, &todo, req.AfterID, req.BeforeID)
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+		return
+	case errors.Is(err, errPermissionDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	case errors.Is(err, errSubtaskPosition):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Subtasks are reordered with PUT /todos/:id/subtasks/order"})
+		return
+	case errors.Is(err, errTodoOrderChanged), isLockConflict(err):
+		c.JSON(http.StatusConflict, gin.H{"error": "The list changed in the meantime. Reload it and try again"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move todo"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Todo moved successfully",
+		"data":    todo,
+	})
+}
@@ -0,0 +1,337 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Edit scopes for recurring todos: scope=this changes one occurrence,
+// scope=all changes the series and its pending occurrences
+const (
+	todoScopeThis = "this"
+	todoScopeAll  = "all"
+)
+
+// RecurrenceInput makes a todo repeat. TimeZone is an IANA name such as
+// Asia/Jakarta and defaults to APP_TIMEZONE.
+type RecurrenceInput struct {
+	RRule    string `json:"rrule"`
+	TimeZone string `json:"time_zone"`
+}
+
+// optionalRecurrence tells a missing recurrence apart from null, which
+// stops the todo repeating
+type optionalRecurrence struct {
+	Set   bool
+	Value *RecurrenceInput
+}
+
+func (o *optionalRecurrence) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
+
+// recurrence is a validated RecurrenceInput
+type recurrence struct {
+	rule     *utils.RRule
+	timeZone string
+	location *time.Location
+}
+
+// parseRecurrence validates the recurrence in the input, if any. It returns
+// nil when the todo should not repeat.
+func parseRecurrence(in optionalRecurrence) (*recurrence, error) {
+	if !in.Set || in.Value == nil {
+		return nil, nil
+	}
+
+	rule, err := utils.ParseRRule(in.Value.RRule)
+	if err != nil {
+		return nil, err
+	}
+	tz := in.Value.TimeZone
+	if tz == "" {
+		tz = config.DefaultTimeZone()
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time_zone %q", tz)
+	}
+	return &recurrence{rule: rule, timeZone: tz, location: loc}, nil
+}
+
+// checkRecurring reports why todo can't repeat by rec, if it can't. A rule
+// that schedules nothing after the due date, such as every February 29th
+// every four years from a non-leap year, is refused here rather than ending
+// the series on the first completion.
+func checkRecurring(todo *models.Todo, rec *recurrence) error {
+	if todo.ParentID != nil {
+		return fmt.Errorf("subtasks can't repeat")
+	}
+	if todo.DueDate == nil {
+		return fmt.Errorf("a recurring todo needs a due date")
+	}
+	if _, ok := rec.rule.Next(*todo.DueDate, *todo.DueDate, rec.location); !ok {
+		return fmt.Errorf("rrule has no occurrence after the due date")
+	}
+	return nil
+}
+
+// todoScope reads the scope query parameter
+func todoScope(c *gin.Context) (string, error) {
+	switch scope := c.DefaultQuery("scope", todoScopeThis); scope {
+	case todoScopeThis, todoScopeAll:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("scope must be this or all")
+	}
+}
+
+// startSeries creates a series that starts at todo's due date and uses the
+// todo as template. The todo must be saved afterwards.
+func startSeries(tx *gorm.DB, todo *models.Todo, rec *recurrence) (*models.TodoSeries, error) {
+	series := models.TodoSeries{
+		UserID:      todo.UserID,
+		RRule:       rec.rule.String(),
+		TimeZone:    rec.timeZone,
+		StartsAt:    *todo.DueDate,
+		Title:       todo.Title,
+		Description: todo.Description,
+		Priority:    todo.Priority,
+		ListID:      todo.ListID,
+	}
+	if err := tx.Create(&series).Error; err != nil {
+		return nil, err
+	}
+
+	occurrence := *todo.DueDate
+	todo.SeriesID = &series.ID
+	todo.OccurrenceAt = &occurrence
+	return &series, nil
+}
+
+// setRecurrence applies a recurrence change to todo. A new rule on an
+// existing series starts counting again from this occurrence, and nil stops
+// the series. It returns the todo's series, if it still has one. The todo
+// must be saved afterwards.
+func setRecurrence(tx *gorm.DB, todo *models.Todo, rec *recurrence) (*models.TodoSeries, error) {
+	if todo.SeriesID == nil {
+		if rec == nil {
+			return nil, nil
+		}
+		return startSeries(tx, todo, rec)
+	}
+
+	if rec == nil {
+		// Done occurrences keep pointing at the series as history
+		if err := tx.Model(&models.Todo{}).
+			Where("series_id = ? AND status = ? AND id <> ?", *todo.SeriesID, "pending", todo.ID).
+			Updates(map[string]interface{}{"series_id": nil, "occurrence_at": nil}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Delete(&models.TodoSeries{}, *todo.SeriesID).Error; err != nil {
+			return nil, err
+		}
+		todo.SeriesID = nil
+		todo.OccurrenceAt = nil
+		return nil, nil
+	}
+
+	var series models.TodoSeries
+	if err := tx.First(&series, *todo.SeriesID).Error; err != nil {
+		return nil, err
+	}
+	if todo.OccurrenceAt == nil {
+		todo.OccurrenceAt = todo.DueDate
+	}
+	series.RRule = rec.rule.String()
+	series.TimeZone = rec.timeZone
+	series.StartsAt = *todo.OccurrenceAt
+	series.EndedAt = nil
+	if err := tx.Save(&series).Error; err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// updateSeriesTemplate copies the fields set in the input from todo to its
+// series and the series' other pending occurrences. Moving the due date
+// moves the series so later occurrences follow the new time.
+func updateSeriesTemplate(tx *gorm.DB, todo *models.Todo, in *TodoInput) error {
+	updates := map[string]interface{}{}
+	if in.Title != nil {
+		updates["title"] = todo.Title
+	}
+	if in.Description != nil {
+		updates["description"] = todo.Description
+	}
+	if in.Priority != nil {
+		updates["priority"] = todo.Priority
+	}
+	if in.ListID.Set {
+		updates["list_id"] = todo.ListID
+	}
+
+	if len(updates) > 0 {
+		if err := tx.Model(&models.Todo{}).
+			Where("series_id = ? AND status = ? AND id <> ?", *todo.SeriesID, "pending", todo.ID).
+			Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	if in.DueDate.Set && todo.DueDate != nil {
+		occurrence := *todo.DueDate
+		todo.OccurrenceAt = &occurrence
+		updates["starts_at"] = occurrence
+		updates["ended_at"] = nil
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return tx.Model(&models.TodoSeries{}).Where("id = ?", *todo.SeriesID).Updates(updates).Error
+}
+
+// advanceSeries creates the occurrence that follows todo in its series: the
+// first one the rule schedules after the given time. It returns nil when the
+// todo doesn't repeat, the series has ended or the next occurrence already
+// exists, such as when a todo is completed a second time.
+func advanceSeries(tx *gorm.DB, todo *models.Todo, after time.Time) (*models.Todo, error) {
+	if todo.SeriesID == nil || todo.OccurrenceAt == nil {
+		return nil, nil
+	}
+
+	var series models.TodoSeries
+	err := tx.First(&series, *todo.SeriesID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if series.EndedAt != nil {
+		return nil, nil
+	}
+
+	var later int64
+	if err := tx.Model(&models.Todo{}).
+		Where("series_id = ? AND occurrence_at > ?", series.ID, *todo.OccurrenceAt).
+		Count(&later).Error; err != nil {
+		return nil, err
+	}
+	if later > 0 {
+		return nil, nil
+	}
+
+	rule, err := utils.ParseRRule(series.RRule)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(series.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	if after.Before(*todo.OccurrenceAt) {
+		after = *todo.OccurrenceAt
+	}
+	at, ok := rule.Next(series.StartsAt, after, loc)
+	if !ok {
+		return nil, tx.Model(&series).Update("ended_at", time.Now()).Error
+	}
+
+	position, err := topTodoPosition(tx, series.UserID, series.ListID)
+	if err != nil {
+		return nil, err
+	}
+
+	due := at
+	next := models.Todo{
+		UserID:       series.UserID,
+		ListID:       series.ListID,
+		Title:        series.Title,
+		Description:  series.Description,
+		Priority:     series.Priority,
+		Status:       "pending",
+		Position:     position,
+		SeriesID:     &series.ID,
+		OccurrenceAt: &at,
+		DueDate:      &due,
+	}
+	if err := tx.Create(&next).Error; err != nil {
+		return nil, err
+	}
+
+	// Tags carry over to the next occurrence
+	var tags []models.Tag
+	if err := tx.Model(todo).Association("Tags").Find(&tags); err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		if err := tx.Model(&next).Association("Tags").Append(tags); err != nil {
+			return nil, err
+		}
+	}
+	return &next, nil
+}
+
+// SkipTodoOccurrence drops a pending occurrence of a recurring todo and
+// creates the one after it
+func SkipTodoOccurrence(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var todo models.Todo
+	if err := config.DB.First(&todo, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+		return
+	}
+
+	// Check ownership
+	if todo.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+	if todo.SeriesID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Todo does not repeat"})
+		return
+	}
+	if todo.Status != "pending" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only pending occurrences can be skipped"})
+		return
+	}
+
+	var next *models.Todo
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		// The zero time asks for the occurrence right after this one
+		if next, err = advanceSeries(tx, &todo, time.Time{}); err != nil {
+			return err
+		}
+		if err := tx.Where("parent_id = ?", todo.ID).Delete(&models.Todo{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&todo).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to skip occurrence"})
+		return
+	}
+
+	if next == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Occurrence skipped; the series has ended"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Occurrence skipped",
+		"next":    next,
+	})
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/policy"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxSubtasks caps the checklist of a single todo
+const maxSubtasks = 100
+
+type ReorderSubtasksRequest struct {
+	IDs []uint `json:"ids" binding:"required"`
+}
+
+// GetTodo returns a todo with its subtasks in checklist order
+func GetTodo(c *gin.Context) {
+	role := c.GetString("role")
+	userID, _ := c.Get("user_id")
+
+	var todo models.Todo
+	err := config.DB.Scopes(models.WithSubtaskCounts).
+		Preload("Series").
+		Preload("Tags").
+		Preload("Subtasks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sort_order ASC, id ASC")
+		}).
+		First(&todo, c.Param("id")).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+		return
+	}
+
+	if todo.UserID != userID.(uint) && !policy.Can(role, policy.TodoReadAny) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": todo})
+}
+
+// CreateSubtask adds a checklist item to the end of a todo's subtasks.
+// Subtasks can't have subtasks of their own, and can't repeat.
+func CreateSubtask(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var parent models.Todo
+	if err := config.DB.First(&parent, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+		return
+	}
+
+	// Check ownership
+	if parent.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+	if parent.ParentID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Subtasks can't have subtasks"})
+		return
+	}
+
+	var input TodoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Title == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title is required"})
+		return
+	}
+	if input.ListID.Set {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Subtasks stay in their parent's list"})
+		return
+	}
+	if input.Recurrence.Set && input.Recurrence.Value != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Subtasks can't repeat"})
+		return
+	}
+	var tags []string
+	if input.Tags != nil {
+		var err error
+		if tags, err = normalizeTagNames(*input.Tags); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var count int64
+	config.DB.Model(&models.Todo{}).Where("parent_id = ?", parent.ID).Count(&count)
+	if count >= maxSubtasks {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A todo can have at most %d subtasks", maxSubtasks)})
+		return
+	}
+
+	subtask := models.Todo{
+		UserID:    parent.UserID,
+		ListID:    parent.ListID,
+		ParentID:  &parent.ID,
+		SortOrder: int(count),
+		Status:    "pending",
+		Priority:  parent.Priority,
+	}
+	if err := input.apply(&subtask); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&subtask).Error; err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			return setTodoTags(tx, &subtask, tags)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create subtask"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Subtask created successfully",
+		"data":    subtask,
+	})
+}
+
+// ReorderSubtasks sets the checklist order of a todo's subtasks. The body
+// must list every subtask exactly once.
+func ReorderSubtasks(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var parent models.Todo
+	if err := config.DB.First(&parent, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+		return
+	}
+
+	// Check ownership
+	if parent.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	var req ReorderSubtasksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var current []uint
+	if err := config.DB.Model(&models.Todo{}).Where("parent_id = ?", parent.ID).Pluck("id", &current).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subtasks"})
+		return
+	}
+
+	if !sameIDs(current, req.IDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids must list every subtask of the todo exactly once"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range req.IDs {
+			if err := tx.Model(&models.Todo{}).Where("id = ?", id).Update("sort_order", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder subtasks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Subtasks reordered successfully"})
+}
+
+// sameIDs reports whether b is a permutation of a without duplicates
+func sameIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[uint]bool, len(a))
+	for _, id := range a {
+		seen[id] = true
+	}
+	for _, id := range b {
+		if !seen[id] {
+			return false
+		}
+		delete(seen, id)
+	}
+	return true
+}
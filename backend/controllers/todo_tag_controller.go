@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxTagsPerTodo caps how many tags one todo can carry
+const maxTagsPerTodo = 20
+
+// todoHasTag matches todos carrying the tag named by the argument
+const todoHasTag = "EXISTS (SELECT 1 FROM todo_tags JOIN tags ON tags.id = todo_tags.tag_id " +
+	"WHERE todo_tags.todo_id = todo.id AND tags.name = ?)"
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type UpdateTagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// normalizeTagNames lowercases, strips # and dedupes tag names
+func normalizeTagNames(names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	var result []string
+	for _, name := range names {
+		name = utils.NormalizeTagName(name)
+		if !utils.TagNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid tag %q: use up to 50 letters, digits, - or _", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	if len(result) > maxTagsPerTodo {
+		return nil, fmt.Errorf("a todo can have at most %d tags", maxTagsPerTodo)
+	}
+	return result, nil
+}
+
+// setTodoTags replaces the todo's tags, creating the user's tags that don't
+// exist yet. Names must already be normalized.
+func setTodoTags(tx *gorm.DB, todo *models.Todo, names []string) error {
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tag := models.Tag{UserID: todo.UserID, Name: name}
+		if err := tx.Where(&tag).FirstOrCreate(&tag).Error; err != nil {
+			return err
+		}
+		tags = append(tags, tag)
+	}
+	if err := tx.Model(todo).Association("Tags").Replace(tags); err != nil {
+		return err
+	}
+	todo.Tags = tags
+	return nil
+}
+
+// likePattern turns user text into a LIKE pattern that matches it anywhere,
+// with its own wildcards taken literally
+func likePattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// todoTermCondition maps one smart filter term to SQL. Every condition is
+// false rather than NULL for missing values, so negating it is safe.
+func todoTermCondition(term utils.TodoQueryTerm, now time.Time, loc *time.Location) (string, []interface{}) {
+	switch term.Key {
+	case utils.TodoQueryTag:
+		return todoHasTag, []interface{}{term.Value}
+	case utils.TodoQueryStatus:
+		return "status = ?", []interface{}{term.Value}
+	case utils.TodoQueryPriority:
+		return "priority IN ?", []interface{}{strings.Split(term.Value, ",")}
+	case utils.TodoQueryList:
+		if term.Value == "none" {
+			return "list_id IS NULL", nil
+		}
+		return "(list_id IS NOT NULL AND list_id = ?)", []interface{}{term.Value}
+	case utils.TodoQueryText:
+		pattern := likePattern(term.Value)
+		return "(title LIKE ? OR COALESCE(description, '') LIKE ?)", []interface{}{pattern, pattern}
+	case utils.TodoQueryBefore, utils.TodoQueryAfter:
+		day, _, _ := utils.TodoDueRange(term.Value, now, loc)
+		if term.Key == utils.TodoQueryBefore {
+			return "(due_date IS NOT NULL AND due_date < ?)", []interface{}{day}
+		}
+		return "(due_date IS NOT NULL AND due_date >= ?)", []interface{}{day}
+	case utils.TodoQueryDue:
+		switch term.Value {
+		case "overdue":
+			return "(status = 'pending' AND due_date IS NOT NULL AND due_date < ?)", []interface{}{now}
+		case "none":
+			return "due_date IS NULL", nil
+		case "any":
+			return "due_date IS NOT NULL", nil
+		}
+		from, to, _ := utils.TodoDueRange(term.Value, now, loc)
+		return "(due_date IS NOT NULL AND due_date >= ? AND due_date < ?)", []interface{}{from, to}
+	}
+	// ParseTodoQuery only returns the keys above
+	return "1 = 0", nil
+}
+
+// applyTodoQuery narrows a todo query by parsed smart filter terms. Dates
+// are days in APP_TIMEZONE.
+func applyTodoQuery(query *gorm.DB, terms []utils.TodoQueryTerm) *gorm.DB {
+	loc := config.DefaultLocation()
+	now := time.Now()
+	for _, term := range terms {
+		cond, args := todoTermCondition(term, now, loc)
+		if term.Negate {
+			cond = "NOT " + cond
+		}
+		query = query.Where(cond, args...)
+	}
+	return query
+}
+
+// GetTags returns the user's tags with how many todos carry each
+func GetTags(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var tags []models.Tag
+	if err := config.DB.Scopes(models.WithTagTodoCounts).
+		Where("tags.user_id = ?", userID).
+		Order("tags.name ASC").
+		Find(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": tags})
+}
+
+// UpdateTag renames one of the user's tags
+func UpdateTag(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var tag models.Tag
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&tag).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+
+	var req UpdateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := utils.NormalizeTagName(req.Name)
+	if !utils.TagNamePattern.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tag names use up to 50 letters, digits, - or _"})
+		return
+	}
+
+	var existing models.Tag
+	err := config.DB.Where("user_id = ? AND name = ? AND id <> ?", userID, name, tag.ID).First(&existing).Error
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have a tag with that name"})
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tag"})
+		return
+	}
+
+	if err := config.DB.Model(&tag).Update("name", name).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tag updated successfully",
+		"data":    tag,
+	})
+}
+
+// DeleteTag removes a tag from all of the user's todos and deletes it
+func DeleteTag(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var tag models.Tag
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&tag).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM todo_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&tag).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+}
@@ -0,0 +1,435 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/policy"
+	"bulan2-backend/utils"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// recoveryCodeCount is how many recovery codes are issued at a time
+const recoveryCodeCount = 10
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorVerifyRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type TwoFactorPolicyRequest struct {
+	Roles []string `json:"roles"`
+}
+
+// totpIssuer returns the issuer name shown in authenticator apps
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "Bulan2"
+}
+
+// mfaPurpose returns the partial token purpose a user must go through
+// before getting a full token, or "" if none is needed
+func mfaPurpose(user *models.User) string {
+	if user.TOTPEnabled {
+		return utils.PurposeMFA
+	}
+	if utils.IsTwoFactorRequired(user.Role) {
+		return utils.PurposeMFASetup
+	}
+	return ""
+}
+
+// respondMFAChallenge answers a successful password check with a partial
+// token instead of a full one
+func respondMFAChallenge(c *gin.Context, user *models.User, purpose string) {
+	mfaToken, err := utils.GeneratePartialToken(user, purpose)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	if purpose == utils.PurposeMFASetup {
+		c.JSON(http.StatusOK, gin.H{
+			"message":            "Two-factor authentication must be set up before logging in",
+			"mfa_setup_required": true,
+			"mfa_token":          mfaToken,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Two-factor authentication code required",
+		"mfa_required": true,
+		"mfa_token":    mfaToken,
+	})
+}
+
+// verifySecondFactor checks a TOTP code or consumes a recovery code
+func verifySecondFactor(user *models.User, code, recoveryCode string) bool {
+	if code != "" {
+		step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now())
+		if !ok {
+			return false
+		}
+		// Each time step can only be used once per user to stop replays
+		key := fmt.Sprintf("totp_used:%d:%d", user.ID, step)
+		used, err := config.RedisClient.SetNX(context.Background(), key, "1", 2*time.Minute).Result()
+		return err == nil && used
+	}
+
+	if recoveryCode != "" {
+		hash := utils.HashToken(utils.NormalizeRecoveryCode(recoveryCode))
+		result := config.DB.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hash).
+			Update("used_at", time.Now())
+		return result.Error == nil && result.RowsAffected == 1
+	}
+
+	return false
+}
+
+// checkLoginLock refuses the request while the account is locked by failed
+// attempts
+func checkLoginLock(c *gin.Context, email string) bool {
+	remaining, err := utils.LoginLockRemaining(email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check account status"})
+		return false
+	}
+	if remaining > 0 {
+		respondAccountLocked(c, remaining)
+		return false
+	}
+	return true
+}
+
+// respondCodeFailure counts a wrong password or code towards the account's
+// lockout and answers with message, or with the lockout once it is reached
+func respondCodeFailure(c *gin.Context, email, message string) {
+	if lock, err := utils.RecordLoginFailure(email); err == nil && lock > 0 {
+		respondAccountLocked(c, lock)
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+}
+
+// replaceRecoveryCodes invalidates old recovery codes and returns new ones
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	records := make([]models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		records[i] = models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(utils.NormalizeRecoveryCode(code)),
+		}
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// SetupTwoFactor generates a new TOTP secret and provisioning URI
+func SetupTwoFactor(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret := utils.GenerateTOTPSecret()
+	if err := config.DB.Model(&user).Update("totp_secret", secret).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set up two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Scan the QR code with your authenticator app, then confirm with a code",
+		"secret":           secret,
+		"provisioning_uri": utils.TOTPProvisioningURI(totpIssuer(), user.Email, secret),
+	})
+}
+
+// EnableTwoFactor confirms enrollment with a code and returns recovery codes
+func EnableTwoFactor(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	claims := c.MustGet("claims").(*utils.Claims)
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Call setup before enabling two-factor authentication"})
+		return
+	}
+
+	if !checkLoginLock(c, user.Email) {
+		return
+	}
+	if !verifySecondFactor(&user, req.Code, "") {
+		respondCodeFailure(c, user.Email, "Invalid authentication code")
+		return
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("totp_enabled", true).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	response := gin.H{
+		"message":        "Two-factor authentication enabled. Store the recovery codes somewhere safe",
+		"recovery_codes": codes,
+	}
+
+	// Forced enrollment finishes the login that was put on hold
+	if claims.Purpose == utils.PurposeMFASetup {
+		utils.RevokeAccessToken(claims)
+
+		token, refreshToken, err := issueTokens(c, &user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		response["token"] = token
+		response["refresh_token"] = refreshToken
+		response["expires_in"] = int(utils.AccessTokenTTL.Seconds())
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// VerifyTwoFactor exchanges a partial login token and a code for a full JWT
+func VerifyTwoFactor(c *gin.Context) {
+	var req TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := utils.ValidateToken(req.MFAToken)
+	if err != nil || claims.Purpose != utils.PurposeMFA || utils.CheckAccessToken(claims) != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login session, please log in again"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, claims.UserID).Error; err != nil || !user.TOTPEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login session, please log in again"})
+		return
+	}
+	if !user.IsActive() {
+		respondAccountSuspended(c)
+		return
+	}
+
+	// Code guesses count towards the same per-account lockout as passwords
+	if !checkLoginLock(c, user.Email) {
+		return
+	}
+
+	if !verifySecondFactor(&user, req.Code, req.RecoveryCode) {
+		respondCodeFailure(c, user.Email, "Invalid authentication code")
+		return
+	}
+
+	utils.ResetLoginFailures(user.Email)
+
+	// The partial token is single-use
+	utils.RevokeAccessToken(claims)
+
+	respondLoginSuccess(c, &user)
+}
+
+// DisableTwoFactor turns 2FA off after checking the password and a code,
+// or just the code for passwordless accounts
+func DisableTwoFactor(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req TwoFactorDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if utils.IsTwoFactorRequired(user.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for your role"})
+		return
+	}
+	// Passwordless accounts give their TOTP code in place of the password,
+	// so checkCurrentPassword is the only check of it. A recovery code is
+	// checked on its own.
+	codeIsPassword := !user.HasPassword() && req.RecoveryCode == ""
+	if user.HasPassword() || codeIsPassword {
+		if !checkCurrentPassword(c, &user, req.Password, req.Code) {
+			return
+		}
+	}
+	if !codeIsPassword {
+		if !checkLoginLock(c, user.Email) {
+			return
+		}
+		if !verifySecondFactor(&user, req.Code, req.RecoveryCode) {
+			respondCodeFailure(c, user.Email, "Invalid authentication code")
+			return
+		}
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_enabled": false,
+			"totp_secret":  "",
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	recordAudit(c, utils.AuditTwoFactorDisable, "user", user.ID, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a TOTP code
+func RegenerateRecoveryCodes(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if !checkLoginLock(c, user.Email) {
+		return
+	}
+	if !verifySecondFactor(&user, req.Code, "") {
+		respondCodeFailure(c, user.Email, "Invalid authentication code")
+		return
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// GetTwoFactorPolicy returns the roles that must use 2FA
+func GetTwoFactorPolicy(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"roles": utils.TwoFactorRequiredRoles()})
+}
+
+// UpdateTwoFactorPolicy sets the roles that must use 2FA
+func UpdateTwoFactorPolicy(c *gin.Context) {
+	var req TwoFactorPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, role := range req.Roles {
+		if !policy.IsRole(role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role: " + role})
+			return
+		}
+	}
+
+	before := utils.TwoFactorRequiredRoles()
+	if err := utils.SetSetting(utils.SettingTwoFactorRoles, strings.Join(req.Roles, ",")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update policy"})
+		return
+	}
+
+	recordAudit(c, utils.AuditSettingChange, "setting", 0,
+		gin.H{utils.SettingTwoFactorRoles: before}, gin.H{utils.SettingTwoFactorRoles: utils.TwoFactorRequiredRoles()})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor policy updated",
+		"roles":   utils.TwoFactorRequiredRoles(),
+	})
+}
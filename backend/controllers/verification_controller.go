@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// sendVerificationEmail emails a signed verification link to the user
+func sendVerificationEmail(user *models.User) {
+	token := utils.GenerateSignedToken(utils.PurposeEmailVerification, user.ID, user.Email, utils.EmailVerificationTTL)
+	link := fmt.Sprintf("%s/verify-email?token=%s", getFrontendURL(), url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+		user.Nama, link, utils.EmailVerificationTTL)
+
+	if err := config.Mail.Send(user.Email, "Verify your email address", body); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+}
+
+// VerifyEmail marks the user's email as verified using a signed link token
+func VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, email, err := utils.ParseSignedToken(utils.PurposeEmailVerification, req.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Where("id = ? AND email = ?", userID, email).First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+
+	if !user.IsVerified() {
+		if err := config.DB.Model(&user).Update("verified_at", time.Now()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerification sends a new verification link to an unverified account
+func ResendVerification(c *gin.Context) {
+	var req ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Where("email = ?", req.Email).First(&user).Error; err == nil && !user.IsVerified() {
+		sendVerificationEmail(&user)
+	}
+
+	// Same response either way to avoid email enumeration
+	c.JSON(http.StatusOK, gin.H{"message": "If the account exists and is unverified, a new verification link has been sent"})
+}
@@ -0,0 +1,62 @@
+// Package jobs runs periodic background work inside the API process
+package jobs
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"bulan2-backend/utils"
+	"context"
+	"log"
+	"time"
+)
+
+// AccountPurgeInterval is how often accounts past their deletion date are purged
+var AccountPurgeInterval = time.Hour
+
+// accountPurgeLock keeps replicas from purging the same accounts concurrently
+const accountPurgeLock = "jobs:account_purge"
+
+// StartAccountPurger purges accounts whose deletion grace period is over,
+// once at startup and then every AccountPurgeInterval until ctx is done
+func StartAccountPurger(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(AccountPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			PurgeDeletedAccounts(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// PurgeDeletedAccounts purges every account that is due for deletion
+func PurgeDeletedAccounts(ctx context.Context) {
+	ok, err := config.RedisClient.SetNX(ctx, accountPurgeLock, "1", AccountPurgeInterval/2).Result()
+	if err != nil || !ok {
+		return
+	}
+	defer config.RedisClient.Del(context.Background(), accountPurgeLock)
+
+	var users []models.User
+	if err := config.DB.Unscoped().Where("delete_after IS NOT NULL AND delete_after <= ?", time.Now()).Find(&users).Error; err != nil {
+		log.Printf("Account purge: failed to list accounts: %v", err)
+		return
+	}
+
+	for _, user := range users {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := utils.PurgeUserData(user.ID); err != nil {
+			log.Printf("Account purge: user %d failed: %v", user.ID, err)
+			continue
+		}
+		log.Printf("Account purge: user %d purged", user.ID)
+	}
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"bulan2-backend/config"
+	"bulan2-backend/jobs"
 	"bulan2-backend/middleware"
 	"bulan2-backend/routes"
+	"bulan2-backend/utils"
 	"context"
 	"log"
 	"net/http"
@@ -22,7 +24,13 @@ func main() {
 		log.Println("No .env file found, using system environment variables")
 	}
 
-	// Initialize database
+	// Initialize JWT signing
+	if err := utils.InitJWT(); err != nil {
+		log.Fatalf("Failed to initialize JWT: %v", err)
+	}
+
+	// Initialize time zones and database
+	config.InitTimeZone()
 	config.InitDatabase()
 	defer config.CloseDatabase()
 
@@ -32,16 +40,29 @@ func main() {
 
 	// Initialize OAuth
 	config.InitOAuth()
+	config.InitOIDC()
+	config.InitLDAP()
+
+	// Initialize mailer
+	config.InitMailer()
+
+	// Start background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	jobs.StartAccountPurger(jobsCtx)
 
 	// Set Gin mode
 	if os.Getenv("APP_ENV") == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// Create Gin router
-	r := gin.Default()
+	// Create Gin router. gin.Default() is not used because its logger
+	// writes raw query strings; middleware.Logger redacts credentials.
+	r := gin.New()
+	r.Use(gin.Recovery())
 
 	// Apply middleware
+	r.Use(middleware.RequestID())
 	r.Use(middleware.CORS())
 	r.Use(middleware.Logger())
 
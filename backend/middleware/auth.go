@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"bulan2-backend/models"
+	"bulan2-backend/policy"
 	"bulan2-backend/utils"
 	"net/http"
 	"strings"
@@ -8,49 +10,155 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware validates JWT token
+// AuthMiddleware validates a JWT or a personal access token. Routes reachable
+// with personal access tokens must be guarded by RequireScope or SessionOnly.
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+		authenticate(c, "")
+	}
+}
+
+// MFASetupAuth accepts full tokens as well as the partial tokens issued to
+// users who must enroll in two-factor auth before they can log in
+func MFASetupAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticate(c, "", utils.PurposeMFASetup)
+	}
+}
+
+// authenticate validates the bearer token, checks that its purpose is one of
+// the allowed ones and stores the user info in the context
+func authenticate(c *gin.Context, allowedPurposes ...string) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		c.Abort()
+		return
+	}
+
+	// Extract token from "Bearer <token>"
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
+		c.Abort()
+		return
+	}
+
+	token := parts[1]
+
+	if utils.IsPersonalAccessToken(token) {
+		authenticateAccessToken(c, token)
+		return
+	}
+
+	// Validate token
+	claims, err := utils.ValidateToken(token)
+	if err != nil || !purposeAllowed(claims.Purpose, allowedPurposes) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		c.Abort()
+		return
+	}
+
+	// Reject tokens that were revoked by logout or a sign-out everywhere
+	if err := utils.CheckAccessToken(claims); err == utils.ErrTokenRevoked {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		c.Abort()
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
+		c.Abort()
+		return
+	}
+
+	if claims.SessionID != 0 {
+		utils.TouchSession(claims.SessionID, c.ClientIP())
+	}
+
+	// Set user info in context
+	c.Set("claims", claims)
+	c.Set("user_id", claims.UserID)
+	c.Set("email", claims.Email)
+	c.Set("role", claims.Role)
+	c.Set("email_verified", claims.EmailVerified)
+
+	if claims.ImpersonatorID != 0 {
+		c.Set("impersonator_id", claims.ImpersonatorID)
+		c.Next()
+		utils.LogImpersonatedRequest(claims, c.Request.Method, c.Request.URL.Path, c.Writer.Status(), c.ClientIP())
+		return
+	}
+
+	c.Next()
+}
+
+// authenticateAccessToken stores the owner of a personal access token in the
+// context. Role and verification status come from the database, so changes
+// apply to tokens immediately.
+func authenticateAccessToken(c *gin.Context, token string) {
+	pat, user, err := utils.AuthenticatePersonalAccessToken(token)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		c.Abort()
+		return
+	}
+
+	c.Set("access_token", pat)
+	c.Set("user_id", user.ID)
+	c.Set("email", user.Email)
+	c.Set("role", user.Role)
+	c.Set("email_verified", user.IsVerified())
+
+	c.Next()
+}
+
+// RequireScope lets personal access tokens through only when they hold the
+// read scope (safe methods) or write scope (everything else) for resource.
+// Requests authenticated with a JWT are not restricted.
+func RequireScope(resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("access_token")
+		if !ok {
+			c.Next()
 			return
 		}
 
-		// Extract token from "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
+		scope := resource + ":write"
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			scope = resource + ":read"
+		}
+
+		if !value.(*models.PersonalAccessToken).HasScope(scope) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Token is missing the required scope",
+				"scope": scope,
+			})
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
-		token := parts[1]
-
-		// Validate token
-		claims, err := utils.ValidateToken(token)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+// SessionOnly rejects personal access tokens, for account and security
+// endpoints that must only be used from an interactive login
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("access_token"); ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Personal access tokens cannot be used for this endpoint"})
 			c.Abort()
 			return
 		}
-
-		// Set user info in context
-		c.Set("user_id", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Set("role", claims.Role)
-
 		c.Next()
 	}
 }
 
-// AdminOnly middleware checks if user is admin
-func AdminOnly() gin.HandlerFunc {
+// DenyImpersonation blocks admins acting as a user from changing that
+// user's credentials and security settings
+func DenyImpersonation() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists || role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		if _, ok := c.Get("impersonator_id"); ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed while impersonating a user"})
 			c.Abort()
 			return
 		}
@@ -58,12 +166,23 @@ func AdminOnly() gin.HandlerFunc {
 	}
 }
 
-// RequireGuru middleware checks if user is guru
-func RequireGuru() gin.HandlerFunc {
+func purposeAllowed(purpose string, allowed []string) bool {
+	for _, p := range allowed {
+		if p == purpose {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission middleware checks that the user's role grants perm
+func RequirePermission(perm policy.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists || role != "guru" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Guru access required"})
+		if !policy.Can(c.GetString("role"), perm) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":      "Permission denied",
+				"permission": perm,
+			})
 			c.Abort()
 			return
 		}
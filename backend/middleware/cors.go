@@ -22,10 +22,10 @@ func CORS() gin.HandlerFunc {
 		config.AllowOrigins = strings.Split(allowedOrigins, ",")
 	}
 	
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
 	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
 	config.AllowCredentials = true
-	config.ExposeHeaders = []string{"Content-Length"}
+	config.ExposeHeaders = []string{"Content-Length", RequestIDHeader}
 
 	return cors.New(config)
 }
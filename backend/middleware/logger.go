@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"log"
+	"net/url"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -12,7 +13,7 @@ func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
-		raw := c.Request.URL.RawQuery
+		raw := redactQuery(c.Request.URL.RawQuery)
 
 		// Process request
 		c.Next()
@@ -26,11 +27,39 @@ func Logger() gin.HandlerFunc {
 			path = path + "?" + raw
 		}
 
-		log.Printf("[%s] %d | %13v | %s",
+		log.Printf("[%s] %d | %13v | %s | %s",
 			method,
 			statusCode,
 			latency,
+			c.GetString("request_id"),
 			path,
 		)
 	}
 }
+
+// sensitiveQueryParams are never written to the request log
+var sensitiveQueryParams = []string{"token", "refreshToken", "refresh_token", "access_token", "mfa_token", "code", "state"}
+
+// redactQuery masks credentials that may appear in a query string
+func redactQuery(raw string) string {
+	if raw == "" {
+		return raw
+	}
+
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return "[unparseable query redacted]"
+	}
+
+	redacted := false
+	for _, key := range sensitiveQueryParams {
+		if _, ok := values[key]; ok {
+			values.Set(key, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return raw
+	}
+	return values.Encode()
+}
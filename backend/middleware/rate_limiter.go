@@ -125,3 +125,27 @@ func LoginRateLimiter() gin.HandlerFunc {
 func RegisterRateLimiter() gin.HandlerFunc {
 	return RateLimitMiddleware(1, time.Minute)
 }
+
+// RefreshRateLimiter creates a rate limiter for token refreshes
+// Default: 30 requests per minute
+func RefreshRateLimiter() gin.HandlerFunc {
+	return RateLimitMiddleware(30, time.Minute)
+}
+
+// MagicLinkRateLimiter creates a rate limiter for login link requests
+// Default: 3 requests per minute
+func MagicLinkRateLimiter() gin.HandlerFunc {
+	return RateLimitMiddleware(3, time.Minute)
+}
+
+// PasswordResetRateLimiter creates a rate limiter for password reset requests
+// Default: 3 requests per minute
+func PasswordResetRateLimiter() gin.HandlerFunc {
+	return RateLimitMiddleware(3, time.Minute)
+}
+
+// ExportRateLimiter creates a rate limiter for personal data exports
+// Default: 2 requests per minute
+func ExportRateLimiter() gin.HandlerFunc {
+	return RateLimitMiddleware(2, time.Minute)
+}
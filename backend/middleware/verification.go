@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"bulan2-backend/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// verificationExemptPaths stay reachable for unverified users under any policy
+var verificationExemptPaths = map[string]bool{
+	"/api/auth/me":     true,
+	"/api/auth/logout": true,
+}
+
+// RequireVerifiedEmail enforces EMAIL_VERIFICATION_POLICY for authenticated users
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		verified := c.GetBool("email_verified")
+		policy := utils.EmailVerificationPolicy()
+
+		if verified || policy == utils.VerificationPolicyOff || verificationExemptPaths[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		if policy == utils.VerificationPolicyReadOnly {
+			switch c.Request.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Email address not verified",
+			"code":  "email_unverified",
+		})
+		c.Abort()
+	}
+}
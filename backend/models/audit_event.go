@@ -0,0 +1,41 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAuditEventImmutable is returned when code tries to change or remove an audit event
+var ErrAuditEventImmutable = errors.New("audit events are append-only")
+
+// AuditEvent records one security-relevant or grading action: who did it,
+// what it was done to and the values before and after
+type AuditEvent struct {
+	ID             uint      `gorm:"primaryKey;type:bigint unsigned" json:"id"`
+	ActorID        *uint     `gorm:"type:bigint unsigned;index" json:"actor_id"`
+	ImpersonatorID *uint     `gorm:"type:bigint unsigned" json:"impersonator_id,omitempty"`
+	Action         string    `gorm:"size:64;not null;index" json:"action"`
+	TargetType     string    `gorm:"size:32;index:idx_audit_target" json:"target_type"`
+	TargetID       uint      `gorm:"type:bigint unsigned;index:idx_audit_target" json:"target_id"`
+	Before         string    `gorm:"type:text" json:"before,omitempty"`
+	After          string    `gorm:"type:text" json:"after,omitempty"`
+	RequestID      string    `gorm:"size:64;index" json:"request_id"`
+	IPAddress      string    `gorm:"size:45" json:"ip_address"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
+
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
+
+// BeforeUpdate keeps the audit trail append-only
+func (AuditEvent) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditEventImmutable
+}
+
+// BeforeDelete keeps the audit trail append-only
+func (AuditEvent) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditEventImmutable
+}
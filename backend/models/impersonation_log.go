@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// ImpersonationLog records one request an admin made while acting as a user
+type ImpersonationLog struct {
+	ID             uint      `gorm:"primaryKey;type:bigint unsigned" json:"id"`
+	ImpersonatorID uint      `gorm:"type:bigint unsigned;not null;index" json:"impersonator_id"`
+	UserID         uint      `gorm:"type:bigint unsigned;not null;index" json:"user_id"`
+	SessionID      uint      `gorm:"type:bigint unsigned;not null" json:"session_id"`
+	Method         string    `gorm:"size:10;not null" json:"method"`
+	Path           string    `gorm:"size:255;not null" json:"path"`
+	Status         int       `json:"status"`
+	IPAddress      string    `gorm:"size:45" json:"ip_address"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
+
+func (ImpersonationLog) TableName() string {
+	return "impersonation_logs"
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordReset stores a hashed single-use password reset token
+type PasswordReset struct {
+	ID        uint       `gorm:"primaryKey;type:bigint unsigned" json:"id"`
+	UserID    uint       `gorm:"type:bigint unsigned;not null;index" json:"user_id"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (PasswordReset) TableName() string {
+	return "password_resets"
+}
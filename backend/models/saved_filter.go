@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SavedFilter is a named todo query in the smart filter syntax, such as
+// "#kuliah due:this-week -is:done"
+type SavedFilter struct {
+	ID        uint           `gorm:"primaryKey;type:bigint unsigned" json:"id"`
+	UserID    uint           `gorm:"type:bigint unsigned;not null;index" json:"user_id"`
+	Name      string         `gorm:"size:100;not null" json:"name"`
+	Query     string         `gorm:"size:500;not null" json:"query"`
+	SortOrder int            `gorm:"not null;default:0" json:"sort_order"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (SavedFilter) TableName() string {
+	return "saved_filters"
+}
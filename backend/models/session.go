@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Session is one signed-in device. Access and refresh tokens carry the
+// session ID so a single session can be signed out remotely.
+type Session struct {
+	ID         uint      `gorm:"primaryKey;type:bigint unsigned" json:"id"`
+	UserID     uint      `gorm:"type:bigint unsigned;not null;index" json:"user_id"`
+	Device     string    `gorm:"size:100" json:"device"`
+	UserAgent  string    `gorm:"size:255" json:"user_agent"`
+	IPAddress  string    `gorm:"size:45" json:"ip_address"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// ImpersonatorID is set when an admin started this session to act as the user
+	ImpersonatorID *uint      `gorm:"type:bigint unsigned" json:"impersonator_id"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
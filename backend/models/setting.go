@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Setting is an application-wide key/value setting changed at runtime by admins
+type Setting struct {
+	Key       string    `gorm:"primaryKey;size:100" json:"key"`
+	Value     string    `gorm:"type:text" json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Setting) TableName() string {
+	return "settings"
+}
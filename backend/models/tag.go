@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tag is a free-form label a user puts on their todos. Names are stored
+// lowercase without the leading #.
+type Tag struct {
+	ID     uint   `gorm:"primaryKey;type:bigint unsigned" json:"id"`
+	UserID uint   `gorm:"type:bigint unsigned;not null;uniqueIndex:idx_tags_user_name,priority:1" json:"user_id"`
+	Name   string `gorm:"size:50;not null;uniqueIndex:idx_tags_user_name,priority:2" json:"name"`
+	// TodoCount is a read-only column filled in by WithTagTodoCounts
+	TodoCount int64     `gorm:"->;-:migration" json:"todo_count,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (Tag) TableName() string {
+	return "tags"
+}
+
+// WithTagTodoCounts selects the tag columns plus how many todos carry each tag
+func WithTagTodoCounts(db *gorm.DB) *gorm.DB {
+	return db.Select("tags.*, " +
+		"(SELECT COUNT(*) FROM todo_tags JOIN todo ON todo.id = todo_tags.todo_id " +
+		"WHERE todo_tags.tag_id = tags.id AND todo.deleted_at IS NULL) AS todo_count")
+}
@@ -6,16 +6,76 @@ import (
 	"gorm.io/gorm"
 )
 
+// Todo priorities, lowest first; the enum order makes ORDER BY priority work
+const (
+	TodoPriorityLow    = "low"
+	TodoPriorityMedium = "medium"
+	TodoPriorityHigh   = "high"
+)
+
 type Todo struct {
-	ID        uint           `gorm:"primaryKey;type:bigint unsigned" json:"id"`
-	UserID    uint           `gorm:"type:bigint unsigned;not null;index" json:"user_id"`
-	User      User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Title     string         `gorm:"size:255;not null" json:"title"`
-	Status    string         `gorm:"type:enum('pending','done');default:'pending'" json:"status"`
-	CreatedAt time.Time      `json:"created_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID          uint       `gorm:"primaryKey;type:bigint unsigned" json:"id"`
+	UserID      uint       `gorm:"type:bigint unsigned;not null;index;index:idx_todos_user_due,priority:1;index:idx_todos_position,priority:1" json:"user_id"`
+	User        User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	ListID      *uint      `gorm:"type:bigint unsigned;index;index:idx_todos_position,priority:2" json:"list_id"`
+	ParentID    *uint      `gorm:"type:bigint unsigned;index" json:"parent_id"`
+	SortOrder   int        `gorm:"not null;default:0" json:"sort_order"`
+	Title       string     `gorm:"size:255;not null" json:"title"`
+	Description string     `gorm:"type:text" json:"description"`
+	Status      string     `gorm:"type:enum('pending','done');default:'pending'" json:"status"`
+	DueDate     *time.Time `gorm:"index:idx_todos_user_due,priority:2" json:"due_date"`
+	Priority    string     `gorm:"type:enum('low','medium','high');not null;default:'medium'" json:"priority"`
+	CompletedAt *time.Time `json:"completed_at"`
+	Overdue     bool       `gorm:"-" json:"overdue"`
+	SeriesID    *uint      `gorm:"type:bigint unsigned;index" json:"series_id"`
+	// Position is the todo's rank in the user's manual order within its
+	// list. Subtasks use SortOrder instead and leave it empty.
+	Position string `gorm:"type:varchar(191) CHARACTER SET ascii COLLATE ascii_bin;not null;default:'';index:idx_todos_position,priority:3" json:"position"`
+	// OccurrenceAt is when the series scheduled this occurrence; DueDate
+	// starts out the same but can be moved without shifting the series
+	OccurrenceAt *time.Time  `json:"occurrence_at"`
+	Series       *TodoSeries `gorm:"foreignKey:SeriesID" json:"series,omitempty"`
+	// Subtask counts are read-only columns filled in by WithSubtaskCounts;
+	// Progress is the percentage of subtasks done, or nil without subtasks
+	SubtaskCount int64          `gorm:"->;-:migration" json:"subtask_count"`
+	SubtasksDone int64          `gorm:"->;-:migration" json:"subtasks_done"`
+	Progress     *int           `gorm:"-" json:"progress"`
+	Subtasks     []Todo         `gorm:"foreignKey:ParentID" json:"subtasks,omitempty"`
+	Tags         []Tag          `gorm:"many2many:todo_tags" json:"tags"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (Todo) TableName() string {
 	return "todo"
 }
+
+// IsOverdue reports whether the todo is still pending past its due date
+func (t *Todo) IsOverdue(now time.Time) bool {
+	return t.Status != "done" && t.DueDate != nil && t.DueDate.Before(now)
+}
+
+// AfterFind fills in Overdue and Progress, which are computed rather than stored
+func (t *Todo) AfterFind(tx *gorm.DB) error {
+	t.Overdue = t.IsOverdue(time.Now())
+	if t.SubtaskCount > 0 {
+		progress := int(t.SubtasksDone * 100 / t.SubtaskCount)
+		t.Progress = &progress
+	}
+	return nil
+}
+
+// AfterSave keeps Overdue current after creates and updates
+func (t *Todo) AfterSave(tx *gorm.DB) error {
+	t.Overdue = t.IsOverdue(time.Now())
+	return nil
+}
+
+// WithSubtaskCounts selects the todo columns plus how many subtasks each
+// todo has and how many of them are done
+func WithSubtaskCounts(db *gorm.DB) *gorm.DB {
+	return db.Select("todo.*, " +
+		"(SELECT COUNT(*) FROM todo AS sub WHERE sub.parent_id = todo.id AND sub.deleted_at IS NULL) AS subtask_count, " +
+		"(SELECT COUNT(*) FROM todo AS sub WHERE sub.parent_id = todo.id AND sub.status = 'done' AND sub.deleted_at IS NULL) AS subtasks_done")
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TodoList groups a user's todos into a project, such as a course
+type TodoList struct {
+	ID        uint   `gorm:"primaryKey;type:bigint unsigned" json:"id"`
+	UserID    uint   `gorm:"type:bigint unsigned;not null;index" json:"user_id"`
+	Name      string `gorm:"size:100;not null" json:"name"`
+	Color     string `gorm:"size:7;not null;default:'#4e73df'" json:"color"`
+	Archived  bool   `gorm:"not null;default:false" json:"archived"`
+	SortOrder int    `gorm:"not null;default:0" json:"sort_order"`
+	// Counts are read-only columns filled in by WithTodoCounts
+	PendingCount int64          `gorm:"->;-:migration" json:"pending_count"`
+	DoneCount    int64          `gorm:"->;-:migration" json:"done_count"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (TodoList) TableName() string {
+	return "todo_lists"
+}
+
+// WithTodoCounts selects the list columns plus the number of pending and
+// done todos in each list
+func WithTodoCounts(db *gorm.DB) *gorm.DB {
+	return db.Select("todo_lists.*, " +
+		"(SELECT COUNT(*) FROM todo WHERE todo.list_id = todo_lists.id AND todo.status = 'pending' AND todo.deleted_at IS NULL) AS pending_count, " +
+		"(SELECT COUNT(*) FROM todo WHERE todo.list_id = todo_lists.id AND todo.status = 'done' AND todo.deleted_at IS NULL) AS done_count")
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TodoSeries is the recurrence behind a repeating todo. Only the current
+// occurrence exists as a Todo; the next one is created from the series when
+// it is completed or skipped.
+type TodoSeries struct {
+	ID     uint `gorm:"primaryKey;type:bigint unsigned" json:"id"`
+	UserID uint `gorm:"type:bigint unsigned;not null;index" json:"user_id"`
+	// RRule is an RFC 5545 RRULE value such as FREQ=WEEKLY;BYDAY=MO
+	RRule string `gorm:"column:rrule;size:500;not null" json:"rrule"`
+	// TimeZone is the IANA zone whose wall clock the occurrences follow
+	TimeZone string    `gorm:"size:64;not null" json:"time_zone"`
+	StartsAt time.Time `gorm:"not null" json:"starts_at"`
+	// Template for new occurrences, changed by edits to all occurrences
+	Title       string         `gorm:"size:255;not null" json:"title"`
+	Description string         `gorm:"type:text" json:"description"`
+	Priority    string         `gorm:"type:enum('low','medium','high');not null;default:'medium'" json:"priority"`
+	ListID      *uint          `gorm:"type:bigint unsigned" json:"list_id"`
+	EndedAt     *time.Time     `json:"ended_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (TodoSeries) TableName() string {
+	return "todo_series"
+}
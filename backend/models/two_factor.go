@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode is a hashed single-use backup code for two-factor login
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey;type:bigint unsigned" json:"id"`
+	UserID    uint       `gorm:"type:bigint unsigned;not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null;index" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
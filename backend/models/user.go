@@ -8,15 +8,22 @@ import (
 )
 
 type User struct {
-	ID        uint           `gorm:"primaryKey;type:bigint unsigned" json:"id"`
-	Nama      string         `gorm:"size:100;not null" json:"nama"`
-	Email     string         `gorm:"size:100;uniqueIndex;not null" json:"email"`
-	Password  string         `gorm:"size:255;not null" json:"-"`
-	Role      string         `gorm:"type:enum('admin','user','guru');default:'user'" json:"role"`
-	Foto      string         `gorm:"size:200" json:"foto"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID           uint           `gorm:"primaryKey;type:bigint unsigned" json:"id"`
+	Nama         string         `gorm:"size:100;not null" json:"nama"`
+	Email        string         `gorm:"size:100;uniqueIndex;not null" json:"email"`
+	Password     string         `gorm:"size:255;not null" json:"-"`
+	Passwordless bool           `gorm:"default:false" json:"-"`
+	Role         string         `gorm:"type:enum('admin','user','guru');default:'user'" json:"role"`
+	Status       string         `gorm:"type:enum('active','suspended');default:'active'" json:"status"`
+	Foto         string         `gorm:"size:200" json:"foto"`
+	VerifiedAt   *time.Time     `json:"verified_at"`
+	PendingEmail string         `gorm:"size:100" json:"pending_email,omitempty"`
+	TOTPSecret   string         `gorm:"column:totp_secret;size:64" json:"-"`
+	TOTPEnabled  bool           `gorm:"column:totp_enabled;default:false" json:"totp_enabled"`
+	DeleteAfter  *time.Time     `json:"delete_after,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // HashPassword hashes the user password using bcrypt
@@ -36,6 +43,22 @@ func (u *User) CheckPassword(password string) bool {
 	return err == nil
 }
 
+// IsVerified reports whether the user has confirmed their email address
+func (u *User) IsVerified() bool {
+	return u.VerifiedAt != nil
+}
+
+// HasPassword reports whether the user knows their password. Accounts
+// created through an external login get a random one until they set their own.
+func (u *User) HasPassword() bool {
+	return !u.Passwordless
+}
+
+// IsActive reports whether the user is allowed to sign in
+func (u *User) IsActive() bool {
+	return u.Status != "suspended"
+}
+
 // TableName overrides the default table name
 func (User) TableName() string {
 	return "users"
@@ -0,0 +1,103 @@
+// Package policy maps roles to named permissions. All authorization
+// decisions go through Can so they can be audited in one place.
+package policy
+
+import "sort"
+
+// Roles
+const (
+	RoleAdmin = "admin"
+	RoleGuru  = "guru"
+	RoleUser  = "user"
+)
+
+// Permission is a named action, written as resource.action[.scope].
+// The .any scope grants the action on records owned by other users.
+type Permission string
+
+const (
+	// Users and students
+	UsersManage Permission = "users.manage"
+	// UsersImpersonate allows acting as another user; never granted for
+	// targets that hold it themselves
+	UsersImpersonate Permission = "users.impersonate"
+	StudentsManage   Permission = "students.manage"
+	SecurityManage   Permission = "security.manage"
+	AuditRead        Permission = "audit.read"
+
+	// Gallery
+	GalleryReadAny   Permission = "gallery.read.any"
+	GalleryDeleteAny Permission = "gallery.delete.any"
+
+	// Todos
+	TodoReadAny   Permission = "todo.read.any"
+	TodoDeleteAny Permission = "todo.delete.any"
+
+	// Comments
+	CommentDeleteAny Permission = "comment.delete.any"
+
+	// Student-teacher relationship
+	MentorRequest Permission = "mentor.request"
+	MentorReview  Permission = "mentor.review"
+
+	// Assignments
+	AssignmentManage Permission = "assignment.manage"
+	AssignmentGrade  Permission = "assignment.grade"
+	AssignmentSubmit Permission = "assignment.submit"
+)
+
+// rolePermissions is the single source of truth for what each role may do.
+// Acting on your own records needs no permission; only the .any scopes do.
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {
+		UsersManage,
+		UsersImpersonate,
+		StudentsManage,
+		SecurityManage,
+		AuditRead,
+		GalleryReadAny,
+		GalleryDeleteAny,
+		TodoReadAny,
+		TodoDeleteAny,
+		CommentDeleteAny,
+	},
+	RoleGuru: {
+		GalleryReadAny,
+		TodoReadAny,
+		MentorReview,
+		AssignmentManage,
+		AssignmentGrade,
+	},
+	RoleUser: {
+		MentorRequest,
+		AssignmentSubmit,
+	},
+}
+
+// Can reports whether the role has the permission
+func Can(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions returns the role's permissions, sorted
+func Permissions(role string) []Permission {
+	perms := append([]Permission{}, rolePermissions[role]...)
+	sort.Slice(perms, func(i, j int) bool { return perms[i] < perms[j] })
+	return perms
+}
+
+// Roles returns every known role
+func Roles() []string {
+	return []string{RoleAdmin, RoleGuru, RoleUser}
+}
+
+// IsRole reports whether role is a known role
+func IsRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
@@ -0,0 +1,88 @@
+package policy
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestCan(t *testing.T) {
+	tests := []struct {
+		role string
+		perm Permission
+		want bool
+	}{
+		{RoleAdmin, UsersManage, true},
+		{RoleAdmin, UsersImpersonate, true},
+		{RoleAdmin, GalleryDeleteAny, true},
+		{RoleAdmin, TodoDeleteAny, true},
+		{RoleAdmin, CommentDeleteAny, true},
+		{RoleAdmin, AssignmentGrade, false},
+		{RoleGuru, GalleryReadAny, true},
+		// A guru could once delete anyone's photo
+		{RoleGuru, GalleryDeleteAny, false},
+		{RoleGuru, TodoReadAny, true},
+		{RoleGuru, TodoDeleteAny, false},
+		{RoleGuru, AssignmentManage, true},
+		{RoleGuru, AssignmentGrade, true},
+		{RoleGuru, MentorReview, true},
+		{RoleGuru, MentorRequest, false},
+		{RoleGuru, UsersImpersonate, false},
+		{RoleUser, MentorRequest, true},
+		{RoleUser, AssignmentSubmit, true},
+		{RoleUser, AssignmentGrade, false},
+		{RoleUser, TodoReadAny, false},
+		{RoleUser, UsersManage, false},
+		{"", UsersManage, false},
+		{"superuser", UsersManage, false},
+		{RoleAdmin, Permission("users.manage.everything"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role+"/"+string(tt.perm), func(t *testing.T) {
+			if got := Can(tt.role, tt.perm); got != tt.want {
+				t.Errorf("Can(%q, %q) = %v, want %v", tt.role, tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImpersonationIsAdminOnly(t *testing.T) {
+	for _, role := range Roles() {
+		if role != RoleAdmin && Can(role, UsersImpersonate) {
+			t.Errorf("role %q can impersonate users", role)
+		}
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	for _, role := range Roles() {
+		perms := Permissions(role)
+		if len(perms) == 0 {
+			t.Errorf("role %q has no permissions", role)
+		}
+		if !sort.SliceIsSorted(perms, func(i, j int) bool { return perms[i] < perms[j] }) {
+			t.Errorf("Permissions(%q) is not sorted", role)
+		}
+		for _, p := range perms {
+			if !Can(role, p) {
+				t.Errorf("Permissions(%q) lists %q but Can denies it", role, p)
+			}
+		}
+	}
+	if perms := Permissions("superuser"); len(perms) != 0 {
+		t.Errorf("Permissions of an unknown role = %v, want none", perms)
+	}
+}
+
+func TestIsRole(t *testing.T) {
+	for _, role := range Roles() {
+		if !IsRole(role) {
+			t.Errorf("IsRole(%q) = false", role)
+		}
+	}
+	for _, role := range []string{"", "Admin", "superuser"} {
+		if IsRole(role) {
+			t.Errorf("IsRole(%q) = true", role)
+		}
+	}
+}
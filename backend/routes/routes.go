@@ -3,6 +3,8 @@ package routes
 import (
 	"bulan2-backend/controllers"
 	"bulan2-backend/middleware"
+	"bulan2-backend/policy"
+	"bulan2-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,6 +16,12 @@ func SetupRoutes(r *gin.Engine) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	// Public keys for verifying access tokens
+	r.GET("/.well-known/jwks.json", controllers.GetJWKS)
+
+	// Credential and security changes are off limits while impersonating
+	noImpersonation := middleware.DenyImpersonation()
+
 	api := r.Group("/api")
 	{
 		// Public routes (no auth required)
@@ -23,79 +31,224 @@ func SetupRoutes(r *gin.Engine) {
 			auth.POST("/register", middleware.RegisterRateLimiter(), controllers.Register)
 			// Apply rate limiting to login to prevent brute force attacks
 			auth.POST("/login", middleware.LoginRateLimiter(), controllers.Login)
+			auth.POST("/refresh", middleware.RefreshRateLimiter(), controllers.RefreshToken)
+
+			// Password recovery
+			auth.POST("/forgot-password", middleware.PasswordResetRateLimiter(), controllers.ForgotPassword)
+			auth.POST("/reset-password", middleware.PasswordResetRateLimiter(), controllers.ResetPassword)
+
+			// Second step of a two-factor login
+			auth.POST("/2fa/verify", middleware.LoginRateLimiter(), controllers.VerifyTwoFactor)
+
+			// Email verification
+			auth.POST("/verify-email", controllers.VerifyEmail)
+			auth.POST("/resend-verification", middleware.PasswordResetRateLimiter(), controllers.ResendVerification)
+			auth.POST("/confirm-email-change", controllers.ConfirmEmailChange)
 			
 			// Google OAuth
 			auth.GET("/google/login", controllers.GoogleLogin)
 			auth.GET("/google/callback", controllers.GoogleCallback)
+
+			// Generic OpenID Connect providers
+			auth.GET("/oidc/providers", controllers.GetOIDCProviders)
+			auth.GET("/oidc/:provider/login", controllers.OIDCLogin)
+			auth.GET("/oidc/:provider/callback", controllers.OIDCCallback)
+			auth.POST("/oauth/exchange", middleware.LoginRateLimiter(), controllers.ExchangeLoginCode)
+			auth.POST("/magic-link", middleware.MagicLinkRateLimiter(), controllers.RequestMagicLink)
+			auth.POST("/magic-link/login", middleware.LoginRateLimiter(), controllers.MagicLinkLogin)
 		}
 
-		// Protected routes (auth required)
+		// Two-factor enrollment, also reachable with a forced-setup login token
+		mfaSetup := api.Group("/auth/2fa")
+		mfaSetup.Use(middleware.MFASetupAuth(), middleware.SessionOnly(), middleware.DenyImpersonation())
+		{
+			mfaSetup.POST("/setup", controllers.SetupTwoFactor)
+			mfaSetup.POST("/enable", controllers.EnableTwoFactor)
+		}
+
+		// Protected routes (auth required). Every group below is either scoped
+		// for personal access tokens or limited to interactive sessions.
 		protected := api.Group("")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(middleware.AuthMiddleware(), middleware.RequireVerifiedEmail())
 		{
-			// Current user
-			protected.GET("/auth/me", controllers.GetCurrentUser)
-			protected.POST("/auth/upload-photo", controllers.UploadProfilePicture)
+			// Account and security endpoints, never reachable with access tokens
+			account := protected.Group("")
+			account.Use(middleware.SessionOnly())
+			{
+				// Current user
+				account.GET("/auth/me", controllers.GetCurrentUser)
+				account.PUT("/auth/me", controllers.UpdateProfile)
+				account.PUT("/auth/me/password", noImpersonation, middleware.LoginRateLimiter(), controllers.ChangePassword)
+				account.PUT("/auth/me/email", noImpersonation, middleware.LoginRateLimiter(), controllers.ChangeEmail)
+				account.GET("/auth/me/export", noImpersonation, middleware.ExportRateLimiter(), controllers.ExportMyData)
+				account.POST("/auth/me/delete", noImpersonation, middleware.LoginRateLimiter(), controllers.RequestAccountDeletion)
+				account.POST("/auth/me/delete/cancel", noImpersonation, controllers.CancelAccountDeletion)
+				account.POST("/auth/logout", controllers.Logout)
+				account.POST("/auth/impersonation/end", controllers.EndImpersonation)
+				account.POST("/auth/upload-photo", controllers.UploadProfilePicture)
+				account.GET("/auth/identities", controllers.GetMyIdentities)
+
+				// Two-factor authentication
+				account.POST("/auth/2fa/disable", noImpersonation, controllers.DisableTwoFactor)
+				account.POST("/auth/2fa/recovery-codes", noImpersonation, controllers.RegenerateRecoveryCodes)
+
+				// Sessions
+				account.GET("/auth/sessions", controllers.GetSessions)
+				account.DELETE("/auth/sessions", noImpersonation, controllers.RevokeOtherSessions)
+				account.DELETE("/auth/sessions/:id", noImpersonation, controllers.RevokeSession)
+
+				// Personal access tokens
+				account.GET("/auth/tokens", controllers.GetAccessTokens)
+				account.GET("/auth/tokens/scopes", controllers.GetAccessTokenScopes)
+				account.POST("/auth/tokens", noImpersonation, controllers.CreateAccessToken)
+				account.DELETE("/auth/tokens/:id", noImpersonation, controllers.RevokeAccessToken)
+
+				// Security policy
+				security := account.Group("/admin")
+				security.Use(middleware.RequirePermission(policy.SecurityManage))
+				{
+					security.GET("/2fa-policy", controllers.GetTwoFactorPolicy)
+					security.PUT("/2fa-policy", controllers.UpdateTwoFactorPolicy)
+					security.POST("/users/unlock", controllers.UnlockAccount)
+				}
+
+				// Audit trail
+				audit := account.Group("/admin/audit-events")
+				audit.Use(middleware.RequirePermission(policy.AuditRead))
+				{
+					audit.GET("", controllers.GetAuditEvents)
+					audit.GET("/export", controllers.ExportAuditEventsCSV)
+				}
+
+				// User management
+				users := account.Group("/admin/users")
+				users.Use(middleware.RequirePermission(policy.UsersManage))
+				{
+					users.GET("", controllers.GetUsers)
+					users.POST("", controllers.CreateUser)
+					users.GET("/:id", controllers.GetUser)
+					users.PUT("/:id/role", controllers.UpdateUserRole)
+					users.POST("/:id/suspend", controllers.SuspendUser)
+					users.POST("/:id/reactivate", controllers.ReactivateUser)
+					users.DELETE("/:id", controllers.DeleteUser)
+					users.POST("/:id/restore", controllers.RestoreUser)
+					users.DELETE("/:id/sessions", controllers.RevokeUserSessions)
+					users.POST("/:id/impersonate", middleware.RequirePermission(policy.UsersImpersonate), controllers.StartImpersonation)
+					users.GET("/impersonation-logs", middleware.RequirePermission(policy.UsersImpersonate), controllers.GetImpersonationLogs)
+				}
+
+				// Student-teacher relationship
+				account.GET("/mahasiswa/guru/all", controllers.GetAllGuru)
+				account.POST("/mahasiswa/guru/request", middleware.RequirePermission(policy.MentorRequest), controllers.RequestGuru)
+				account.GET("/mahasiswa/guru/my", controllers.GetMyGuru)
+
+				mentor := account.Group("/guru")
+				mentor.Use(middleware.RequirePermission(policy.MentorReview))
+				{
+					mentor.GET("/requests", controllers.GetMahasiswaGuruRequests)
+					mentor.POST("/requests/:id/approve", controllers.ApproveMahasiswaGuru)
+					mentor.POST("/requests/:id/reject", controllers.RejectMahasiswaGuru)
+					mentor.GET("/mahasiswa", controllers.GetGuruMahasiswa)
+				}
+			}
 
 			// Gallery
-			protected.GET("/gallery", controllers.GetGallery)
-			protected.POST("/gallery/upload", controllers.UploadPhotos)
-			protected.DELETE("/gallery/:id", controllers.DeletePhoto)
+			gallery := protected.Group("/gallery")
+			gallery.Use(middleware.RequireScope(utils.ScopeGallery))
+			{
+				gallery.GET("", controllers.GetGallery)
+				gallery.POST("/upload", controllers.UploadPhotos)
+				gallery.DELETE("/:id", controllers.DeletePhoto)
+			}
 
 			// Todo
-			protected.GET("/todos", controllers.GetTodos)
-			protected.POST("/todos", controllers.CreateTodo)
-			protected.PUT("/todos/:id/status", controllers.ToggleTodoStatus)
-			protected.DELETE("/todos/:id", controllers.DeleteTodo)
+			todos := protected.Group("/todos")
+			todos.Use(middleware.RequireScope(utils.ScopeTodos))
+			{
+				todos.GET("", controllers.GetTodos)
+				todos.POST("", controllers.CreateTodo)
+				todos.POST("/batch", controllers.BatchTodos)
+				todos.GET("/:id", controllers.GetTodo)
+				todos.PUT("/:id", controllers.UpdateTodo)
+				todos.PATCH("/:id", controllers.UpdateTodo)
+				todos.PUT("/:id/status", controllers.ToggleTodoStatus)
+				todos.POST("/:id/skip", controllers.SkipTodoOccurrence)
+				todos.POST("/:id/move", controllers.MoveTodo)
+				todos.DELETE("/:id", controllers.DeleteTodo)
+				todos.POST("/:id/subtasks", controllers.CreateSubtask)
+				todos.PUT("/:id/subtasks/order", controllers.ReorderSubtasks)
+			}
 
-			// Comments
-			protected.GET("/comments", controllers.GetComments)
-			protected.POST("/comments", controllers.CreateComment)
-			protected.DELETE("/comments/:id", controllers.DeleteComment)
+			// Todo tags
+			tags := protected.Group("/tags")
+			tags.Use(middleware.RequireScope(utils.ScopeTodos))
+			{
+				tags.GET("", controllers.GetTags)
+				tags.PUT("/:id", controllers.UpdateTag)
+				tags.DELETE("/:id", controllers.DeleteTag)
+			}
 
-			// Admin only routes
-			admin := protected.Group("")
-			admin.Use(middleware.AdminOnly())
+			// Saved smart filters, run with GET /todos?filter_id=<id>
+			savedFilters := protected.Group("/todo-filters")
+			savedFilters.Use(middleware.RequireScope(utils.ScopeTodos))
 			{
-				// Students management
-				admin.GET("/students", controllers.GetStudents)
-				admin.GET("/students/:id", controllers.GetStudent)
-				admin.POST("/students", controllers.CreateStudent)
-				admin.PUT("/students/:id", controllers.UpdateStudent)
-				admin.DELETE("/students/:id", controllers.DeleteStudent)
-				admin.GET("/students/export/csv", controllers.ExportStudentsCSV)
+				savedFilters.GET("", controllers.GetSavedFilters)
+				savedFilters.POST("", controllers.CreateSavedFilter)
+				savedFilters.PUT("/:id", controllers.UpdateSavedFilter)
+				savedFilters.PATCH("/:id", controllers.UpdateSavedFilter)
+				savedFilters.DELETE("/:id", controllers.DeleteSavedFilter)
 			}
 
-			// Guru only routes
-			guru := protected.Group("")
-			guru.Use(middleware.RequireGuru())
+			// Todo lists
+			todoLists := protected.Group("/todo-lists")
+			todoLists.Use(middleware.RequireScope(utils.ScopeTodos))
 			{
-				// Student-teacher relationship
-				guru.GET("/guru/requests", controllers.GetMahasiswaGuruRequests)
-				guru.POST("/guru/requests/:id/approve", controllers.ApproveMahasiswaGuru)
-				guru.POST("/guru/requests/:id/reject", controllers.RejectMahasiswaGuru)
-				guru.GET("/guru/mahasiswa", controllers.GetGuruMahasiswa)
-
-				// Assignments
-				guru.POST("/guru/assignments", controllers.CreateAssignment)
-				guru.GET("/guru/assignments", controllers.GetGuruAssignments)
-				guru.GET("/guru/assignments/:id/submissions", controllers.GetAssignmentSubmissions)
-				guru.POST("/guru/assignments/:id/grade", controllers.GradeSubmission)
-				guru.DELETE("/guru/assignments/:id", controllers.DeleteAssignment)
+				todoLists.GET("", controllers.GetTodoLists)
+				todoLists.POST("", controllers.CreateTodoList)
+				todoLists.GET("/:id", controllers.GetTodoList)
+				todoLists.PUT("/:id", controllers.UpdateTodoList)
+				todoLists.PATCH("/:id", controllers.UpdateTodoList)
+				todoLists.DELETE("/:id", controllers.DeleteTodoList)
 			}
 
-			// Mahasiswa routes (user role)
-			mahasiswa := protected.Group("")
+			// Comments
+			comments := protected.Group("/comments")
+			comments.Use(middleware.RequireScope(utils.ScopeComments))
 			{
-				// Student-teacher relationship
-				mahasiswa.GET("/mahasiswa/guru/all", controllers.GetAllGuru)
-				mahasiswa.POST("/mahasiswa/guru/request", controllers.RequestGuru)
-				mahasiswa.GET("/mahasiswa/guru/my", controllers.GetMyGuru)
-
-				// Assignments
-				mahasiswa.GET("/mahasiswa/assignments", controllers.GetMahasiswaAssignments)
-				mahasiswa.GET("/mahasiswa/assignments/:id", controllers.GetAssignmentDetail)
-				mahasiswa.POST("/mahasiswa/assignments/:id/submit", controllers.SubmitAssignment)
+				comments.GET("", controllers.GetComments)
+				comments.POST("", controllers.CreateComment)
+				comments.DELETE("/:id", controllers.DeleteComment)
+			}
+
+			// Students management
+			students := protected.Group("/students")
+			students.Use(middleware.RequireScope(utils.ScopeStudents), middleware.RequirePermission(policy.StudentsManage))
+			{
+				students.GET("", controllers.GetStudents)
+				students.GET("/:id", controllers.GetStudent)
+				students.POST("", controllers.CreateStudent)
+				students.PUT("/:id", controllers.UpdateStudent)
+				students.DELETE("/:id", controllers.DeleteStudent)
+				students.GET("/export/csv", controllers.ExportStudentsCSV)
+			}
+
+			// Assignments
+			assignments := protected.Group("")
+			assignments.Use(middleware.RequireScope(utils.ScopeAssignments))
+			{
+				guru := assignments.Group("/guru/assignments")
+				guru.Use(middleware.RequirePermission(policy.AssignmentManage))
+				{
+					guru.POST("", controllers.CreateAssignment)
+					guru.GET("", controllers.GetGuruAssignments)
+					guru.GET("/:id/submissions", controllers.GetAssignmentSubmissions)
+					guru.DELETE("/:id", controllers.DeleteAssignment)
+				}
+				assignments.POST("/guru/assignments/:id/grade", middleware.RequirePermission(policy.AssignmentGrade), controllers.GradeSubmission)
+
+				assignments.GET("/mahasiswa/assignments", controllers.GetMahasiswaAssignments)
+				assignments.GET("/mahasiswa/assignments/:id", controllers.GetAssignmentDetail)
+				assignments.POST("/mahasiswa/assignments/:id/submit", middleware.RequirePermission(policy.AssignmentSubmit), controllers.SubmitAssignment)
 			}
 		}
 	}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"encoding/json"
+	"log"
+)
+
+// Audit actions. Names are <target>.<verb> so they can be filtered by prefix.
+const (
+	AuditLogin             = "auth.login"
+	AuditLoginFailed       = "auth.login_failed"
+	AuditPasswordChange    = "auth.password_change"
+	AuditTwoFactorDisable  = "auth.2fa_disable"
+	AuditUserCreate        = "user.create"
+	AuditUserRoleChange    = "user.role_change"
+	AuditUserSuspend       = "user.suspend"
+	AuditUserReactivate    = "user.reactivate"
+	AuditUserDelete        = "user.delete"
+	AuditUserRestore       = "user.restore"
+	AuditUserUnlock        = "user.unlock"
+	AuditUserImpersonate   = "user.impersonate"
+	AuditImpersonationEnd  = "user.impersonate_end"
+	AuditAccountExport     = "account.export"
+	AuditAccountDelete     = "account.delete_request"
+	AuditSettingChange     = "setting.change"
+	AuditMentorshipApprove = "mentorship.approve"
+	AuditMentorshipReject  = "mentorship.reject"
+	AuditSubmissionGrade   = "submission.grade"
+	AuditAssignmentDelete  = "assignment.delete"
+	AuditStudentDelete     = "student.delete"
+	AuditStudentExport     = "student.export"
+	AuditAuditExport       = "audit.export"
+)
+
+// AuditEntry describes an action to be written to the audit trail. Before
+// and After are stored as JSON; leave them nil when there is nothing to show.
+type AuditEntry struct {
+	ActorID        uint
+	ImpersonatorID uint
+	Action         string
+	TargetType     string
+	TargetID       uint
+	Before         interface{}
+	After          interface{}
+	RequestID      string
+	IPAddress      string
+}
+
+// RecordAudit appends an entry to the audit trail. Failures are logged but
+// never fail the request that triggered them.
+func RecordAudit(entry AuditEntry) {
+	event := models.AuditEvent{
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		Before:     auditJSON(entry.Before),
+		After:      auditJSON(entry.After),
+		RequestID:  entry.RequestID,
+		IPAddress:  entry.IPAddress,
+	}
+	if entry.ActorID != 0 {
+		event.ActorID = &entry.ActorID
+	}
+	if entry.ImpersonatorID != 0 {
+		event.ImpersonatorID = &entry.ImpersonatorID
+	}
+
+	if err := config.DB.Create(&event).Error; err != nil {
+		log.Printf("Failed to record audit event %s: %v", entry.Action, err)
+	}
+}
+
+func auditJSON(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Failed to encode audit value: %v", err)
+		return ""
+	}
+	return string(b)
+}
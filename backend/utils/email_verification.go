@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"os"
+	"time"
+)
+
+// Email verification policies, selected with EMAIL_VERIFICATION_POLICY
+const (
+	// VerificationPolicyOff lets unverified users do everything
+	VerificationPolicyOff = "off"
+	// VerificationPolicyReadOnly lets unverified users log in but only read
+	VerificationPolicyReadOnly = "read_only"
+	// VerificationPolicyBlock refuses login until the email is verified
+	VerificationPolicyBlock = "block"
+)
+
+// EmailVerificationTTL is how long a verification link stays valid
+var EmailVerificationTTL = 48 * time.Hour
+
+// EmailVerificationPolicy returns the configured policy, defaulting to off
+func EmailVerificationPolicy() string {
+	switch policy := os.Getenv("EMAIL_VERIFICATION_POLICY"); policy {
+	case VerificationPolicyReadOnly, VerificationPolicyBlock:
+		return policy
+	default:
+		return VerificationPolicyOff
+	}
+}
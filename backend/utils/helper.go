@@ -1,6 +1,10 @@
 package utils
 
 import (
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"math/rand"
 	"strconv"
 	"time"
@@ -27,3 +31,20 @@ func ParseInt(s string, defaultValue int) int {
 	}
 	return val
 }
+
+// GenerateSecureToken returns a URL-safe random string built from n bytes
+// of crypto/rand output. Use it for anything an attacker must not guess.
+func GenerateSecureToken(n int) string {
+	b := make([]byte, n)
+	if _, err := cryptorand.Read(b); err != nil {
+		panic("crypto/rand unavailable: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// HashToken returns the hex SHA-256 digest of a token so it can be stored
+// or used as a lookup key without keeping the token itself
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
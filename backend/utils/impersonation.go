@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"log"
+)
+
+// LogImpersonatedRequest records a request made with an impersonation token
+// against the admin who made it
+func LogImpersonatedRequest(claims *Claims, method, path string, status int, ip string) {
+	if len(path) > 255 {
+		path = path[:255]
+	}
+
+	log.Printf("Impersonation: admin %d as user %d: %s %s -> %d", claims.ImpersonatorID, claims.UserID, method, path, status)
+
+	entry := models.ImpersonationLog{
+		ImpersonatorID: claims.ImpersonatorID,
+		UserID:         claims.UserID,
+		SessionID:      claims.SessionID,
+		Method:         method,
+		Path:           path,
+		Status:         status,
+		IPAddress:      ip,
+	}
+	if err := config.DB.Create(&entry).Error; err != nil {
+		log.Printf("Failed to record impersonated request: %v", err)
+	}
+}
@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"bulan2-backend/models"
 	"errors"
+	"fmt"
+	"log"
 	"os"
 	"time"
 
@@ -9,45 +12,202 @@ import (
 )
 
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID        uint   `json:"user_id"`
+	Email         string `json:"email"`
+	Role          string `json:"role"`
+	EmailVerified bool   `json:"email_verified"`
+	// Purpose is empty for full access tokens. Partial tokens issued
+	// half-way through a login carry one of the Purpose* values.
+	Purpose string `json:"purpose,omitempty"`
+	// SessionID ties full access tokens to the session they were issued for
+	SessionID uint `json:"sid,omitempty"`
+	// ImpersonatorID is the admin acting as the user, for impersonation tokens
+	ImpersonatorID uint `json:"impersonator_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
-var jwtSecret []byte
+// Purposes of partial tokens
+const (
+	// PurposeMFA tokens can only be exchanged for a full token with a 2FA code
+	PurposeMFA = "mfa"
+	// PurposeMFASetup tokens can only reach the 2FA enrollment endpoints
+	PurposeMFASetup = "mfa_setup"
+)
+
+// PartialTokenTTL is how long a user has to finish a multi-step login
+var PartialTokenTTL = 5 * time.Minute
+
+// ImpersonationTTL is how long an admin can act as another user per token
+var ImpersonationTTL = 30 * time.Minute
+
+// MaxAccessTokenTTL is the longest any access token lives. Revocations have
+// to be remembered at least this long.
+func MaxAccessTokenTTL() time.Duration {
+	if ImpersonationTTL > AccessTokenTTL {
+		return ImpersonationTTL
+	}
+	return AccessTokenTTL
+}
+
+var (
+	jwtSecret []byte
+
+	// AccessTokenTTL is how long an access token stays valid
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is how long a refresh token can be exchanged
+	RefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// defaultJWTSecrets are the placeholders shipped in code and config templates
+var defaultJWTSecrets = map[string]bool{
+	"default-secret-key-change-this":                      true,
+	"your-secret-key-change-this":                         true,
+	"your-super-secret-jwt-key-change-this-in-production": true,
+	"CHANGE_ME_VERY_LONG_RANDOM_STRING":                   true,
+}
 
 func init() {
+	// Millisecond iat lets a revocation cut-off separate tokens issued
+	// moments before it from tokens issued right after it
+	jwt.TimePrecision = time.Millisecond
+}
+
+// InitJWT loads the token settings from the environment. With JWT_KEYS_DIR
+// set, tokens are signed with RS256/EdDSA keys from that directory and the
+// public keys are published as a JWKS; otherwise JWT_SECRET signs HS256.
+// In production it refuses to start with a missing or placeholder secret.
+func InitJWT() error {
+	production := os.Getenv("APP_ENV") == "production"
+
 	secret := os.Getenv("JWT_SECRET")
+	keysDir := os.Getenv("JWT_KEYS_DIR")
+
+	if production {
+		if defaultJWTSecrets[secret] {
+			return errors.New("JWT_SECRET is still set to a default value")
+		}
+		// The secret also signs email links unless EMAIL_TOKEN_SECRET is set
+		if secret == "" && (keysDir == "" || os.Getenv("EMAIL_TOKEN_SECRET") == "") {
+			return errors.New("JWT_SECRET must be set, or JWT_KEYS_DIR together with EMAIL_TOKEN_SECRET")
+		}
+	}
 	if secret == "" {
 		secret = "default-secret-key-change-this"
+		log.Println("WARNING: JWT_SECRET not set, using an insecure default secret")
 	}
 	jwtSecret = []byte(secret)
+
+	if ttl, err := time.ParseDuration(os.Getenv("JWT_ACCESS_TTL")); err == nil && ttl > 0 {
+		AccessTokenTTL = ttl
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("JWT_REFRESH_TTL")); err == nil && ttl > 0 {
+		RefreshTokenTTL = ttl
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("IMPERSONATION_TTL")); err == nil && ttl > 0 {
+		ImpersonationTTL = ttl
+	}
+
+	if keysDir != "" {
+		if err := loadJWTKeys(keysDir, os.Getenv("JWT_ACTIVE_KID")); err != nil {
+			return fmt.Errorf("loading JWT keys: %w", err)
+		}
+		log.Printf("JWT signing with key %q (%s), %d key(s) published", activeKey.ID, activeKey.Method.Alg(), len(verificationKeys))
+	}
+
+	return nil
+}
+
+// signClaims signs claims with the active key, or HS256 when no key ring is configured
+func signClaims(claims *Claims) (string, error) {
+	if activeKey == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	}
+
+	token := jwt.NewWithClaims(activeKey.Method, claims)
+	token.Header["kid"] = activeKey.ID
+	return token.SignedString(activeKey.Private)
 }
 
-// GenerateToken generates a new JWT token
-func GenerateToken(userID uint, email, role string) (string, error) {
+// GenerateToken generates a new short-lived JWT access token for a session
+func GenerateToken(user *models.User, sessionID uint) (string, error) {
+	now := time.Now()
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:        user.ID,
+		Email:         user.Email,
+		Role:          user.Role,
+		EmailVerified: user.IsVerified(),
+		SessionID:     sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        GenerateSecureToken(16),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return signClaims(claims)
 }
 
-// ValidateToken validates and parses a JWT token
+// GenerateImpersonationToken generates an access token that lets an admin act
+// as user within an impersonation session. It cannot be refreshed.
+func GenerateImpersonationToken(user *models.User, impersonatorID, sessionID uint) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		UserID:         user.ID,
+		Email:          user.Email,
+		Role:           user.Role,
+		EmailVerified:  user.IsVerified(),
+		SessionID:      sessionID,
+		ImpersonatorID: impersonatorID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        GenerateSecureToken(16),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ImpersonationTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	return signClaims(claims)
+}
+
+// GeneratePartialToken generates a short-lived token that only allows the
+// given next step of a login
+func GeneratePartialToken(user *models.User, purpose string) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		UserID:        user.ID,
+		Email:         user.Email,
+		Role:          user.Role,
+		EmailVerified: user.IsVerified(),
+		Purpose:       purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        GenerateSecureToken(16),
+			ExpiresAt: jwt.NewNumericDate(now.Add(PartialTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	return signClaims(claims)
+}
+
+// ValidateToken validates and parses a JWT token. With a key ring, only
+// tokens signed by a known kid with that key's algorithm are accepted.
 func ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if activeKey == nil {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, errors.New("unexpected signing method")
+			}
+			return jwtSecret, nil
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		key, ok := verificationKeys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, errors.New("unexpected signing method")
 		}
-		return jwtSecret, nil
+		return key.Public, nil
 	})
 
 	if err != nil {
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtKey is one entry of the signing key ring. Keys without a private half
+// are kept only to verify tokens signed before a rotation.
+type jwtKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+var (
+	// activeKey signs new tokens; nil means HS256 with jwtSecret
+	activeKey *jwtKey
+	// verificationKeys holds every key tokens are accepted from, by kid
+	verificationKeys = map[string]*jwtKey{}
+)
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// loadJWTKeys reads every <kid>.pem file in dir. Files may hold an RSA or
+// Ed25519 private key, or just a public key for a retired kid.
+func loadJWTKeys(dir, activeKID string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no *.pem keys found in %s", dir)
+	}
+
+	keys := map[string]*jwtKey{}
+	var signingKIDs []string
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := parseJWTKeyFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		key.ID = kid
+		keys[kid] = key
+		if key.Private != nil {
+			signingKIDs = append(signingKIDs, kid)
+		}
+	}
+
+	// Without JWT_ACTIVE_KID the newest kid wins, so date-named files
+	// (e.g. 2026-10.pem) rotate by simply adding a file
+	if activeKID == "" {
+		if len(signingKIDs) == 0 {
+			return errors.New("no private key available for signing")
+		}
+		sort.Strings(signingKIDs)
+		activeKID = signingKIDs[len(signingKIDs)-1]
+	}
+
+	active, ok := keys[activeKID]
+	if !ok || active.Private == nil {
+		return fmt.Errorf("active key %q not found or has no private key", activeKID)
+	}
+
+	activeKey = active
+	verificationKeys = keys
+	return nil
+}
+
+func parseJWTKeyFile(path string) (*jwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &jwtKey{Method: jwt.SigningMethodRS256, Private: k, Public: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &jwtKey{Method: jwt.SigningMethodRS256, Public: k}, nil
+	case ed25519.PrivateKey:
+		return &jwtKey{Method: jwt.SigningMethodEdDSA, Private: k, Public: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &jwtKey{Method: jwt.SigningMethodEdDSA, Public: k}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", parsed)
+}
+
+// JWKS returns the public keys other services can verify our tokens with
+func JWKS() []JWK {
+	kids := make([]string, 0, len(verificationKeys))
+	for kid := range verificationKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	encode := base64.RawURLEncoding.EncodeToString
+	keys := make([]JWK, 0, len(kids))
+	for _, kid := range kids {
+		key := verificationKeys[kid]
+		jwk := JWK{Kid: kid, Alg: key.Method.Alg(), Use: "sig"}
+
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = encode(pub.N.Bytes())
+			jwk.E = encode(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = encode(pub)
+		}
+		keys = append(keys, jwk)
+	}
+	return keys
+}
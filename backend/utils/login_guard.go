@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// MaxFailedLogins is how many failures in a row are allowed before an account locks
+	MaxFailedLogins int64 = 5
+	// LockoutBase is the first lockout duration; it doubles with every further failure
+	LockoutBase = time.Minute
+	// LockoutMax caps the exponential backoff
+	LockoutMax = time.Hour
+	// failureWindow is how long failed attempts are remembered
+	failureWindow = 24 * time.Hour
+)
+
+// OnAccountLocked is called whenever an account gets locked. Replace it to
+// plug in other notification channels.
+var OnAccountLocked = notifyAccountLocked
+
+func init() {
+	if n, err := strconv.ParseInt(os.Getenv("LOGIN_MAX_ATTEMPTS"), 10, 64); err == nil && n > 0 {
+		MaxFailedLogins = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("LOGIN_LOCKOUT_BASE")); err == nil && d > 0 {
+		LockoutBase = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("LOGIN_LOCKOUT_MAX")); err == nil && d > 0 {
+		LockoutMax = d
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// EmailHash identifies an email address in logs without storing it
+func EmailHash(email string) string {
+	return HashToken(normalizeEmail(email))
+}
+
+func loginFailuresKey(email string) string {
+	return "login_failures:" + normalizeEmail(email)
+}
+
+func loginLockKey(email string) string {
+	return "login_lock:" + normalizeEmail(email)
+}
+
+// LoginLockRemaining returns how long the account stays locked, or 0
+func LoginLockRemaining(email string) (time.Duration, error) {
+	ttl, err := config.RedisClient.TTL(storeCtx, loginLockKey(email)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// RecordLoginFailure counts a failed attempt for the account and locks it
+// once MaxFailedLogins is reached. It returns the lock duration, or 0.
+func RecordLoginFailure(email string) (time.Duration, error) {
+	var failures *redis.IntCmd
+	_, err := config.RedisClient.TxPipelined(storeCtx, func(pipe redis.Pipeliner) error {
+		failures = pipe.Incr(storeCtx, loginFailuresKey(email))
+		pipe.Expire(storeCtx, loginFailuresKey(email), failureWindow)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	n := failures.Val()
+	if n < MaxFailedLogins {
+		return 0, nil
+	}
+
+	// Exponential backoff: base, 2x base, 4x base, ... up to LockoutMax
+	lock := LockoutBase
+	for i := MaxFailedLogins; i < n && lock < LockoutMax; i++ {
+		lock *= 2
+	}
+	if lock > LockoutMax {
+		lock = LockoutMax
+	}
+
+	if err := config.RedisClient.Set(storeCtx, loginLockKey(email), n, lock).Err(); err != nil {
+		return 0, err
+	}
+
+	go OnAccountLocked(normalizeEmail(email), n, time.Now().Add(lock))
+	return lock, nil
+}
+
+// ResetLoginFailures clears failed attempts and any lock for the account
+func ResetLoginFailures(email string) error {
+	return config.RedisClient.Del(storeCtx, loginFailuresKey(email), loginLockKey(email)).Err()
+}
+
+// notifyAccountLocked logs the lock and warns the account owner by email
+func notifyAccountLocked(email string, failures int64, until time.Time) {
+	log.Printf("Account %s locked until %s after %d failed login attempts", email, until.Format(time.RFC3339), failures)
+
+	// Only registered addresses get mail, so guesses don't turn into spam
+	var user models.User
+	if err := config.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		return
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nYour account was temporarily locked after %d failed login attempts. You can try again after %s.\n\nIf this wasn't you, consider resetting your password.\n",
+		user.Nama, failures, until.Format("2006-01-02 15:04 MST"))
+	if err := config.Mail.Send(user.Email, "Your account has been temporarily locked", body); err != nil {
+		log.Printf("Failed to send lockout email to user %d: %v", user.ID, err)
+	}
+}
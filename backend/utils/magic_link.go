@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"bulan2-backend/config"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrInvalidMagicLink is returned when a login link is unknown, expired,
+// already used or opened in a different browser than the one that asked for it
+var ErrInvalidMagicLink = errors.New("invalid or expired login link")
+
+// MagicLinkCooldown is the minimum time between two links for one account,
+// so a single address can't be flooded from many IPs
+var MagicLinkCooldown = time.Minute
+
+// magicLinkRecord is what Redis keeps for an outstanding login link
+type magicLinkRecord struct {
+	UserID      uint   `json:"user_id"`
+	BindingHash string `json:"binding_hash"`
+}
+
+func magicLinkKey(hash string) string {
+	return "magic_link:" + hash
+}
+
+func magicLinkSentKey(userID uint) string {
+	return fmt.Sprintf("magic_link_sent:%d", userID)
+}
+
+// IssueMagicLink creates a single-use login token for the user. The token
+// only works together with the returned binding, which the caller keeps in
+// the requesting browser. It returns ok=false while the cooldown is running.
+func IssueMagicLink(userID uint, ttl time.Duration) (token, binding string, ok bool, err error) {
+	ok, err = config.RedisClient.SetNX(storeCtx, magicLinkSentKey(userID), 1, MagicLinkCooldown).Result()
+	if err != nil || !ok {
+		return "", "", false, err
+	}
+
+	token = GenerateSecureToken(32)
+	binding = GenerateSecureToken(32)
+	record := magicLinkRecord{UserID: userID, BindingHash: HashToken(binding)}
+	if err := config.CacheSetJSON(magicLinkKey(HashToken(token)), record, ttl); err != nil {
+		return "", "", false, err
+	}
+	return token, binding, true, nil
+}
+
+// ConsumeMagicLink atomically removes a login token and returns its user if
+// the binding matches. A mismatched binding still burns the token.
+func ConsumeMagicLink(token, binding string) (uint, error) {
+	raw, err := config.RedisClient.GetDel(storeCtx, magicLinkKey(HashToken(token))).Bytes()
+	if err == redis.Nil {
+		return 0, ErrInvalidMagicLink
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var record magicLinkRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return 0, err
+	}
+	if binding == "" || subtle.ConstantTimeCompare([]byte(record.BindingHash), []byte(HashToken(binding))) != 1 {
+		return 0, ErrInvalidMagicLink
+	}
+	return record.UserID, nil
+}
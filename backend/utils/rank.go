@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// rankDigits are the base-62 digits of a rank in ASCII order, so ranks
+// compare correctly as plain strings under a binary collation
+const rankDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// MaxRankLength is how long a rank may grow before the ranks around it
+// should be spread out again
+const MaxRankLength = 64
+
+// RankBetween returns a rank that sorts strictly between a and b. An empty
+// a means before every rank and an empty b after every rank. Ranks never end
+// in the lowest digit, so there is always room for another rank below.
+func RankBetween(a, b string) (string, error) {
+	if err := checkRank(a); err != nil {
+		return "", err
+	}
+	if err := checkRank(b); err != nil {
+		return "", err
+	}
+	if b != "" && a >= b {
+		return "", fmt.Errorf("rank %q is not before %q", a, b)
+	}
+	return rankMidpoint(a, b), nil
+}
+
+// SpreadRanks returns n evenly spaced, increasing ranks
+func SpreadRanks(n int) []string {
+	width, space := 1, len(rankDigits)
+	for space <= n+1 {
+		width++
+		space *= len(rankDigits)
+	}
+	step := space / (n + 1)
+
+	ranks := make([]string, n)
+	for i := range ranks {
+		v := (i + 1) * step
+		buf := make([]byte, width)
+		for j := width - 1; j >= 0; j-- {
+			buf[j] = rankDigits[v%len(rankDigits)]
+			v /= len(rankDigits)
+		}
+		// Keep the no-trailing-zero rule so RankBetween can go below it
+		ranks[i] = strings.TrimRight(string(buf), "0")
+	}
+	return ranks
+}
+
+// rankMidpoint works digit by digit: it copies the common prefix, then
+// takes the middle digit if there is a gap, or recurses one digit deeper
+func rankMidpoint(a, b string) string {
+	if b != "" {
+		n := 0
+		for n < len(b) && rankDigitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + rankMidpoint(rest, b[n:])
+		}
+	}
+
+	da := strings.IndexByte(rankDigits, rankDigitAt(a, 0))
+	db := len(rankDigits)
+	if b != "" {
+		db = strings.IndexByte(rankDigits, b[0])
+	}
+	if db-da > 1 {
+		return string(rankDigits[(da+db)/2])
+	}
+	// The first digits are adjacent. b's first digit alone still sorts
+	// after a, and before b since b has more digits.
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(rankDigits[da]) + rankMidpoint(rest, "")
+}
+
+func rankDigitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return rankDigits[0]
+}
+
+func checkRank(s string) error {
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(rankDigits, s[i]) < 0 {
+			return fmt.Errorf("invalid rank %q", s)
+		}
+	}
+	if strings.HasSuffix(s, rankDigits[:1]) {
+		return fmt.Errorf("invalid rank %q", s)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRankBetween(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty list", a: "", b: "", want: "V"},
+		{name: "before the first", a: "", b: "V", want: "F"},
+		{name: "after the last", a: "V", b: "", want: "k"},
+		{name: "middle digit", a: "A", b: "C", want: "B"},
+		{name: "adjacent digits go deeper", a: "A", b: "B", want: "AV"},
+		{name: "common prefix", a: "aA", b: "aC", want: "aB"},
+		{name: "shorter b", a: "Az", b: "B", want: "AzV"},
+		{name: "longer b", a: "A", b: "B5", want: "B"},
+		{name: "below the lowest single digit", a: "", b: "1", want: "0V"},
+		{name: "after the highest digit", a: "z", b: "", want: "zV"},
+		{name: "a after b", a: "C", b: "A", wantErr: true},
+		{name: "equal", a: "B", b: "B", wantErr: true},
+		{name: "trailing zero", a: "A0", b: "", wantErr: true},
+		{name: "invalid digit", a: "a-b", b: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RankBetween(tt.a, tt.b)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("RankBetween(%q, %q) = %q, want an error", tt.a, tt.b, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RankBetween(%q, %q): %v", tt.a, tt.b, err)
+			}
+			if got != tt.want {
+				t.Errorf("RankBetween(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+			if got <= tt.a || (tt.b != "" && got >= tt.b) {
+				t.Errorf("RankBetween(%q, %q) = %q, not between them", tt.a, tt.b, got)
+			}
+		})
+	}
+}
+
+func TestRankBetweenKeepsOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	ranks := []string{}
+	for i := 0; i < 2000; i++ {
+		at := rng.Intn(len(ranks) + 1)
+		var a, b string
+		if at > 0 {
+			a = ranks[at-1]
+		}
+		if at < len(ranks) {
+			b = ranks[at]
+		}
+		r, err := RankBetween(a, b)
+		if err != nil {
+			t.Fatalf("RankBetween(%q, %q): %v", a, b, err)
+		}
+		ranks = append(ranks[:at], append([]string{r}, ranks[at:]...)...)
+	}
+	if !sort.StringsAreSorted(ranks) {
+		t.Fatal("ranks are out of order")
+	}
+	for i := 1; i < len(ranks); i++ {
+		if ranks[i] == ranks[i-1] {
+			t.Fatalf("rank %q given out twice", ranks[i])
+		}
+	}
+}
+
+func TestSpreadRanks(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 61, 62, 1000} {
+		ranks := SpreadRanks(n)
+		if len(ranks) != n {
+			t.Fatalf("SpreadRanks(%d) returned %d ranks", n, len(ranks))
+		}
+		for i, r := range ranks {
+			if err := checkRank(r); err != nil || r == "" {
+				t.Fatalf("SpreadRanks(%d)[%d] = %q is not a valid rank", n, i, r)
+			}
+			if strings.HasSuffix(r, "0") {
+				t.Fatalf("SpreadRanks(%d)[%d] = %q ends in the lowest digit", n, i, r)
+			}
+			if i > 0 && ranks[i-1] >= r {
+				t.Fatalf("SpreadRanks(%d) is not increasing at %d: %q, %q", n, i, ranks[i-1], r)
+			}
+		}
+	}
+}
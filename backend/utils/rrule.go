@@ -0,0 +1,489 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRRuleSearchYears bounds how far past the given time Next looks for an
+// occurrence. Rules that only match rarely, such as February 29th, still
+// match well within it, and rules that never match give up quickly.
+const maxRRuleSearchYears = 30
+
+// rruleMonthLengths is the longest each month can be, leap years included
+var rruleMonthLengths = [13]int{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// Recurrence frequencies supported in RRULE FREQ
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// rruleWeekday is a BYDAY entry such as MO, 2TU or -1FR. N is 0 when the
+// entry has no ordinal.
+type rruleWeekday struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// RRule is a parsed iCalendar (RFC 5545) recurrence rule. It supports FREQ
+// DAILY to YEARLY with INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH
+// and WKST, which covers what the todo UI offers.
+type RRule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	ByDay      []rruleWeekday
+	ByMonthDay []int
+	ByMonth    []int
+	WeekStart  time.Weekday
+
+	// untilRaw is UNTIL as written; floating times and dates are resolved
+	// against the series time zone in Next
+	untilRaw string
+}
+
+// ParseRRule parses an RRULE value, with or without the "RRULE:" prefix
+func ParseRRule(s string) (*RRule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("rrule is empty")
+	}
+
+	r := &RRule{Interval: 1, WeekStart: time.Monday}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid rrule part %q", part)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("rrule has %s more than once", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			switch value {
+			case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+				r.Freq = value
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %s", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+			if err != nil || r.Interval < 1 || r.Interval > 1000 {
+				return nil, fmt.Errorf("INTERVAL must be between 1 and 1000")
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+			if err != nil || r.Count < 1 {
+				return nil, fmt.Errorf("COUNT must be a positive number")
+			}
+		case "UNTIL":
+			if _, err := parseRRuleUntil(value, time.UTC); err != nil {
+				return nil, err
+			}
+			r.untilRaw = value
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				day, err := parseRRuleWeekday(v)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseRRuleInts(value, -31, 31)
+			if err != nil {
+				return nil, fmt.Errorf("BYMONTHDAY: %w", err)
+			}
+		case "BYMONTH":
+			r.ByMonth, err = parseRRuleInts(value, 1, 12)
+			if err != nil {
+				return nil, fmt.Errorf("BYMONTH: %w", err)
+			}
+		case "WKST":
+			day, ok := rruleWeekdays[value]
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST %s", value)
+			}
+			r.WeekStart = day
+		default:
+			return nil, fmt.Errorf("unsupported rrule part %s", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("rrule needs a FREQ")
+	}
+	if r.Count > 0 && r.untilRaw != "" {
+		return nil, fmt.Errorf("rrule can't have both COUNT and UNTIL")
+	}
+	for _, day := range r.ByDay {
+		if day.N == 0 {
+			continue
+		}
+		if r.Freq != FreqMonthly && !(r.Freq == FreqYearly && len(r.ByMonth) > 0) {
+			return nil, fmt.Errorf("numbered BYDAY such as 2MO needs FREQ=MONTHLY, or YEARLY with BYMONTH")
+		}
+		if day.N < -5 || day.N > 5 {
+			return nil, fmt.Errorf("BYDAY ordinal must be between -5 and 5")
+		}
+	}
+	if r.Freq == FreqWeekly && len(r.ByMonthDay) > 0 {
+		return nil, fmt.Errorf("BYMONTHDAY can't be used with FREQ=WEEKLY")
+	}
+	if r.Freq == FreqYearly && len(r.ByDay) > 0 && len(r.ByMonth) == 0 {
+		return nil, fmt.Errorf("BYDAY with FREQ=YEARLY needs BYMONTH")
+	}
+	if !r.monthDayFits() {
+		return nil, fmt.Errorf("BYMONTHDAY never falls in the months of BYMONTH")
+	}
+
+	return r, nil
+}
+
+// String formats the rule back into its canonical RRULE value
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if r.untilRaw != "" {
+		parts = append(parts, "UNTIL="+r.untilRaw)
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = strings.ToUpper(d.Weekday.String()[:2])
+			if d.N != 0 {
+				days[i] = strconv.Itoa(d.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+strings.ToUpper(r.WeekStart.String()[:2]))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence strictly after the given time for a
+// series that starts at dtstart. Dates are worked out on the wall clock of
+// loc, so a 07:00 todo stays at 07:00 across daylight saving changes no
+// matter which zone the server or database runs in. dtstart itself is the
+// first occurrence and counts towards COUNT. ok is false once the series
+// has ended.
+func (r *RRule) Next(dtstart, after time.Time, loc *time.Location) (next time.Time, ok bool) {
+	start := dtstart.In(loc)
+	var until *time.Time
+	if r.untilRaw != "" {
+		u, _ := parseRRuleUntil(r.untilRaw, loc)
+		until = &u
+	}
+
+	if start.After(after) {
+		return start, true
+	}
+
+	// Without COUNT there is nothing to count, so start at the period
+	// holding after instead of walking every period since dtstart
+	first := 0
+	if r.Count == 0 {
+		first = max(r.periodIndex(start, after), 0)
+	}
+	last := max(r.periodIndex(start, after.AddDate(maxRRuleSearchYears, 0, 0)), first+2)
+
+	n := 1
+	for period := first; period <= last; period++ {
+		for _, day := range r.periodDays(start, period) {
+			t := time.Date(day.Year(), day.Month(), day.Day(),
+				start.Hour(), start.Minute(), start.Second(), 0, loc)
+			if !t.After(start) {
+				continue
+			}
+			if until != nil && t.After(*until) {
+				return time.Time{}, false
+			}
+			n++
+			if r.Count > 0 && n > r.Count {
+				return time.Time{}, false
+			}
+			if t.After(after) {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// periodIndex returns which period after the one holding start holds t,
+// negative when t is before start
+func (r *RRule) periodIndex(start, t time.Time) int {
+	t = t.In(start.Location())
+	var units int
+	switch r.Freq {
+	case FreqDaily:
+		units = rruleDaysBetween(start, t)
+	case FreqWeekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		units = floorDiv(rruleDaysBetween(start, t)+offset, 7)
+	case FreqMonthly:
+		units = (t.Year()-start.Year())*12 + int(t.Month()) - int(start.Month())
+	case FreqYearly:
+		units = t.Year() - start.Year()
+	}
+	return floorDiv(units, r.Interval)
+}
+
+// monthDayFits reports whether some BYMONTHDAY exists in some month the rule
+// allows. Days that only exist in some years, such as the 29th of February,
+// count as fitting.
+func (r *RRule) monthDayFits() bool {
+	if len(r.ByMonthDay) == 0 || len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		for _, md := range r.ByMonthDay {
+			if md <= rruleMonthLengths[m] && -md <= rruleMonthLengths[m] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// periodDays lists the matching days, in order, of the period-th period
+// after the one holding start. Days are midnight in start's location.
+func (r *RRule) periodDays(start time.Time, period int) []time.Time {
+	loc := start.Location()
+	step := period * r.Interval
+	var days []time.Time
+
+	switch r.Freq {
+	case FreqDaily:
+		day := time.Date(start.Year(), start.Month(), start.Day()+step, 0, 0, 0, 0, loc)
+		if r.matchesMonth(day) && r.matchesMonthDay(day) && r.matchesWeekday(day) {
+			days = append(days, day)
+		}
+
+	case FreqWeekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := time.Date(start.Year(), start.Month(), start.Day()-offset+7*step, 0, 0, 0, 0, loc)
+		for i := 0; i < 7; i++ {
+			day := weekStart.AddDate(0, 0, i)
+			if !r.matchesMonth(day) {
+				continue
+			}
+			if len(r.ByDay) == 0 && day.Weekday() != start.Weekday() {
+				continue
+			}
+			if len(r.ByDay) > 0 && !r.matchesWeekday(day) {
+				continue
+			}
+			days = append(days, day)
+		}
+
+	case FreqMonthly:
+		month := time.Date(start.Year(), start.Month()+time.Month(step), 1, 0, 0, 0, 0, loc)
+		if r.matchesMonth(month) {
+			days = r.monthDays(month, start)
+		}
+
+	case FreqYearly:
+		year := start.Year() + step
+		months := r.ByMonth
+		if len(months) == 0 {
+			if len(r.ByMonthDay) > 0 {
+				months = []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+			} else {
+				months = []int{int(start.Month())}
+			}
+		}
+		sorted := append([]int(nil), months...)
+		sort.Ints(sorted)
+		for _, m := range sorted {
+			days = append(days, r.monthDays(time.Date(year, time.Month(m), 1, 0, 0, 0, 0, loc), start)...)
+		}
+	}
+
+	return days
+}
+
+// monthDays lists the days of the month starting at first that match
+// BYMONTHDAY and BYDAY, or start's day of the month when neither is set.
+// Months too short for that day are skipped, as RFC 5545 requires.
+func (r *RRule) monthDays(first, start time.Time) []time.Time {
+	last := first.AddDate(0, 1, -1).Day()
+	var days []time.Time
+	for d := 1; d <= last; d++ {
+		day := first.AddDate(0, 0, d-1)
+		switch {
+		case len(r.ByMonthDay) == 0 && len(r.ByDay) == 0:
+			if d != start.Day() {
+				continue
+			}
+		default:
+			if !r.matchesMonthDay(day) || !r.matchesMonthWeekday(day, last) {
+				continue
+			}
+		}
+		days = append(days, day)
+	}
+	return days
+}
+
+func (r *RRule) matchesMonth(day time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if time.Month(m) == day.Month() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RRule) matchesMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+	for _, md := range r.ByMonthDay {
+		if md == day.Day() || (md < 0 && last+md+1 == day.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWeekday checks BYDAY entries without looking at ordinals
+func (r *RRule) matchesWeekday(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, d := range r.ByDay {
+		if d.Weekday == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMonthWeekday checks BYDAY entries with ordinals counted within the
+// month, so 2TU is the second Tuesday and -1FR the last Friday
+func (r *RRule) matchesMonthWeekday(day time.Time, lastDay int) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	nth := (day.Day()-1)/7 + 1
+	nthFromEnd := -((lastDay-day.Day())/7 + 1)
+	for _, d := range r.ByDay {
+		if d.Weekday != day.Weekday() {
+			continue
+		}
+		if d.N == 0 || d.N == nth || d.N == nthFromEnd {
+			return true
+		}
+	}
+	return false
+}
+
+// rruleDaysBetween counts calendar days from a to b, ignoring the time of day
+func rruleDaysBetween(a, b time.Time) int {
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da) / (24 * time.Hour))
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+func parseRRuleWeekday(s string) (rruleWeekday, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return rruleWeekday{}, fmt.Errorf("invalid BYDAY %q", s)
+	}
+	day, ok := rruleWeekdays[s[len(s)-2:]]
+	if !ok {
+		return rruleWeekday{}, fmt.Errorf("invalid BYDAY %q", s)
+	}
+	n := 0
+	if prefix := s[:len(s)-2]; prefix != "" {
+		var err error
+		n, err = strconv.Atoi(prefix)
+		if err != nil || n == 0 {
+			return rruleWeekday{}, fmt.Errorf("invalid BYDAY %q", s)
+		}
+	}
+	return rruleWeekday{N: n, Weekday: day}, nil
+}
+
+func parseRRuleInts(s string, min, max int) ([]int, error) {
+	var values []int
+	for _, v := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < min || n > max || n == 0 {
+			return nil, fmt.Errorf("invalid value %q", v)
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
+
+// parseRRuleUntil reads UNTIL as a UTC time (…Z), a floating time in loc, or
+// a date meaning the end of that day in loc
+func parseRRuleUntil(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", s, loc); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseInLocation("20060102", s, loc); err == nil {
+		return d.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q, use YYYYMMDD or YYYYMMDDTHHMMSSZ", s)
+}
+
+func joinInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		{name: "prefix and lowercase", rule: "RRULE:freq=weekly;byday=mo,we", want: "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{name: "canonical order", rule: "BYDAY=-1FR;FREQ=MONTHLY;INTERVAL=2", want: "FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR"},
+		{name: "default interval dropped", rule: "FREQ=DAILY;INTERVAL=1;COUNT=5", want: "FREQ=DAILY;COUNT=5"},
+		{name: "february 29th", rule: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", want: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29"},
+		{name: "week start", rule: "FREQ=WEEKLY;WKST=SU", want: "FREQ=WEEKLY;WKST=SU"},
+		{name: "empty", rule: "", wantErr: true},
+		{name: "missing freq", rule: "INTERVAL=2", wantErr: true},
+		{name: "unsupported freq", rule: "FREQ=HOURLY", wantErr: true},
+		{name: "zero interval", rule: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{name: "repeated part", rule: "FREQ=DAILY;FREQ=WEEKLY", wantErr: true},
+		{name: "count and until", rule: "FREQ=DAILY;COUNT=2;UNTIL=20260101", wantErr: true},
+		{name: "numbered weekly byday", rule: "FREQ=WEEKLY;BYDAY=2MO", wantErr: true},
+		{name: "ordinal out of range", rule: "FREQ=MONTHLY;BYDAY=6MO", wantErr: true},
+		{name: "yearly byday without bymonth", rule: "FREQ=YEARLY;BYDAY=MO", wantErr: true},
+		{name: "weekly bymonthday", rule: "FREQ=WEEKLY;BYMONTHDAY=1", wantErr: true},
+		{name: "february 30th", rule: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", wantErr: true},
+		{name: "april 31st", rule: "FREQ=MONTHLY;BYMONTH=4;BYMONTHDAY=31,-31", wantErr: true},
+		{name: "unknown part", rule: "FREQ=DAILY;BYHOUR=9", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRRule(tt.rule)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseRRule(%q) = %s, want an error", tt.rule, r)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRRule(%q): %v", tt.rule, err)
+			}
+			if got := r.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRRuleNext(t *testing.T) {
+	jakarta, _ := time.LoadLocation("Asia/Jakarta")
+	newYork, _ := time.LoadLocation("America/New_York")
+	at := func(loc *time.Location, y int, m time.Month, d, h int) time.Time {
+		return time.Date(y, m, d, h, 0, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name   string
+		rule   string
+		loc    *time.Location
+		start  time.Time
+		after  time.Time
+		want   time.Time
+		wantOK bool
+	}{
+		{
+			name:  "start is the first occurrence",
+			rule:  "FREQ=DAILY",
+			loc:   jakarta,
+			start: at(jakarta, 2026, 1, 1, 9), after: at(jakarta, 2025, 12, 31, 9),
+			want: at(jakarta, 2026, 1, 1, 9), wantOK: true,
+		},
+		{
+			name:  "every other day",
+			rule:  "FREQ=DAILY;INTERVAL=2",
+			loc:   jakarta,
+			start: at(jakarta, 2026, 1, 1, 9), after: at(jakarta, 2026, 1, 2, 0),
+			want: at(jakarta, 2026, 1, 3, 9), wantOK: true,
+		},
+		{
+			name:  "bymonthday 31 skips short months",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=31",
+			loc:   jakarta,
+			start: at(jakarta, 2026, 1, 31, 9), after: at(jakarta, 2026, 1, 31, 9),
+			want: at(jakarta, 2026, 3, 31, 9), wantOK: true,
+		},
+		{
+			name:  "bymonthday 31 after march",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=31",
+			loc:   jakarta,
+			start: at(jakarta, 2026, 1, 31, 9), after: at(jakarta, 2026, 3, 31, 9),
+			want: at(jakarta, 2026, 5, 31, 9), wantOK: true,
+		},
+		{
+			name:  "last day of the month",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			loc:   jakarta,
+			start: at(jakarta, 2026, 1, 31, 9), after: at(jakarta, 2026, 1, 31, 9),
+			want: at(jakarta, 2026, 2, 28, 9), wantOK: true,
+		},
+		{
+			name:  "last friday",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR",
+			loc:   jakarta,
+			start: at(jakarta, 2026, 1, 30, 9), after: at(jakarta, 2026, 1, 30, 9),
+			want: at(jakarta, 2026, 2, 27, 9), wantOK: true,
+		},
+		{
+			name:  "second tuesday",
+			rule:  "FREQ=MONTHLY;BYDAY=2TU",
+			loc:   jakarta,
+			start: at(jakarta, 2026, 1, 13, 9), after: at(jakarta, 2026, 1, 13, 9),
+			want: at(jakarta, 2026, 2, 10, 9), wantOK: true,
+		},
+		{
+			name:  "weekly on two days",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,FR",
+			loc:   jakarta,
+			start: at(jakarta, 2026, 1, 5, 9), after: at(jakarta, 2026, 1, 5, 9),
+			want: at(jakarta, 2026, 1, 9, 9), wantOK: true,
+		},
+		{
+			name:  "weekly long after the start",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,FR",
+			loc:   jakarta,
+			start: at(jakarta, 2020, 1, 6, 9), after: at(jakarta, 2026, 1, 6, 0),
+			want: at(jakarta, 2026, 1, 9, 9), wantOK: true,
+		},
+		{
+			name:  "count counts the start",
+			rule:  "FREQ=DAILY;COUNT=3",
+			loc:   jakarta,
+			start: at(jakarta, 2026, 1, 1, 9), after: at(jakarta, 2026, 1, 2, 9),
+			want: at(jakarta, 2026, 1, 3, 9), wantOK: true,
+		},
+		{
+			name:  "count exhausted",
+			rule:  "FREQ=DAILY;COUNT=3",
+			loc:   jakarta,
+			start: at(jakarta, 2026, 1, 1, 9), after: at(jakarta, 2026, 1, 3, 9),
+		},
+		{
+			name:  "until is inclusive",
+			rule:  "FREQ=DAILY;UNTIL=20260103T090000",
+			loc:   jakarta,
+			start: at(jakarta, 2026, 1, 1, 9), after: at(jakarta, 2026, 1, 2, 9),
+			want: at(jakarta, 2026, 1, 3, 9), wantOK: true,
+		},
+		{
+			name:  "until passed",
+			rule:  "FREQ=DAILY;UNTIL=20260103",
+			loc:   jakarta,
+			start: at(jakarta, 2026, 1, 1, 9), after: at(jakarta, 2026, 1, 3, 9),
+		},
+		{
+			name:  "february 29th waits for a leap year",
+			rule:  "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29",
+			loc:   jakarta,
+			start: at(jakarta, 2024, 2, 29, 9), after: at(jakarta, 2024, 2, 29, 9),
+			want: at(jakarta, 2028, 2, 29, 9), wantOK: true,
+		},
+		{
+			name:  "february 29th never reached",
+			rule:  "FREQ=YEARLY;INTERVAL=4;BYMONTH=2;BYMONTHDAY=29",
+			loc:   jakarta,
+			start: at(jakarta, 2025, 2, 1, 9), after: at(jakarta, 2025, 2, 1, 9),
+		},
+		{
+			name:  "keeps wall clock time into daylight saving",
+			rule:  "FREQ=DAILY",
+			loc:   newYork,
+			start: at(newYork, 2026, 3, 7, 7), after: at(newYork, 2026, 3, 7, 7),
+			want: at(newYork, 2026, 3, 8, 7), wantOK: true,
+		},
+		{
+			name:  "keeps wall clock time out of daylight saving",
+			rule:  "FREQ=WEEKLY",
+			loc:   newYork,
+			start: at(newYork, 2026, 10, 26, 7), after: at(newYork, 2026, 10, 26, 7),
+			want: at(newYork, 2026, 11, 2, 7), wantOK: true,
+		},
+		{
+			name:  "start given in another zone",
+			rule:  "FREQ=DAILY",
+			loc:   newYork,
+			start: at(newYork, 2026, 3, 7, 7).UTC(), after: at(newYork, 2026, 3, 7, 7),
+			want: at(newYork, 2026, 3, 8, 7), wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRRule(%q): %v", tt.rule, err)
+			}
+			got, ok := r.Next(tt.start, tt.after, tt.loc)
+			if ok != tt.wantOK {
+				t.Fatalf("Next() ok = %v (%s), want %v", ok, got, tt.wantOK)
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("Next() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRRuleNextGivesUpQuickly(t *testing.T) {
+	r, err := ParseRRule("FREQ=YEARLY;INTERVAL=4;BYMONTH=2;BYMONTHDAY=29")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, 2, 1, 9, 0, 0, 0, time.UTC)
+
+	began := time.Now()
+	if _, ok := r.Next(start, start, time.UTC); ok {
+		t.Fatal("Next() found an occurrence for a rule that never matches")
+	}
+	if took := time.Since(began); took > 100*time.Millisecond {
+		t.Errorf("Next() took %s to give up", took)
+	}
+}
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrSessionRevoked is returned when a session was signed out or has expired
+var ErrSessionRevoked = errors.New("session has been revoked")
+
+// sessionSeenResolution limits how often last_seen_at is written
+var sessionSeenResolution = time.Minute
+
+func revokedSessionKey(sessionID uint) string {
+	return fmt.Sprintf("revoked_session:%d", sessionID)
+}
+
+func sessionSeenKey(sessionID uint) string {
+	return fmt.Sprintf("session_seen:%d", sessionID)
+}
+
+// StartSession records a new signed-in device for the user
+func StartSession(userID uint, userAgent, ip string) (*models.Session, error) {
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	session := models.Session{
+		UserID:     userID,
+		Device:     DescribeDevice(userAgent),
+		UserAgent:  userAgent,
+		IPAddress:  ip,
+		LastSeenAt: time.Now(),
+	}
+	if err := config.DB.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// StartImpersonationSession records a session in which an admin acts as the
+// user. It shows up in the user's own session list.
+func StartImpersonationSession(userID, impersonatorID uint, userAgent, ip string) (*models.Session, error) {
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	session := models.Session{
+		UserID:         userID,
+		Device:         fmt.Sprintf("Admin impersonation (user %d)", impersonatorID),
+		UserAgent:      userAgent,
+		IPAddress:      ip,
+		LastSeenAt:     time.Now(),
+		ImpersonatorID: &impersonatorID,
+	}
+	if err := config.DB.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ActiveSessionsQuery returns the user's sessions that are neither revoked
+// nor idle for longer than a refresh token lives
+func ActiveSessionsQuery(userID uint) *gorm.DB {
+	return config.DB.Where("user_id = ? AND revoked_at IS NULL AND last_seen_at > ?",
+		userID, time.Now().Add(-RefreshTokenTTL))
+}
+
+// GetActiveSession loads one of the user's active sessions
+func GetActiveSession(userID, sessionID uint) (*models.Session, error) {
+	var session models.Session
+	if err := ActiveSessionsQuery(userID).First(&session, sessionID).Error; err != nil {
+		return nil, ErrSessionRevoked
+	}
+	return &session, nil
+}
+
+// TouchSession updates the session's last-seen time and IP, at most once
+// per sessionSeenResolution
+func TouchSession(sessionID uint, ip string) {
+	ok, err := config.RedisClient.SetNX(storeCtx, sessionSeenKey(sessionID), "1", sessionSeenResolution).Result()
+	if err != nil || !ok {
+		return
+	}
+	config.DB.Model(&models.Session{}).Where("id = ?", sessionID).
+		UpdateColumns(map[string]interface{}{"last_seen_at": time.Now(), "ip_address": ip})
+}
+
+// RevokeSession signs a single session out. Its refresh token stops working
+// right away and its access tokens are rejected until they expire.
+func RevokeSession(session *models.Session) error {
+	now := time.Now()
+	if err := config.DB.Model(session).Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return config.CacheSet(revokedSessionKey(session.ID), "1", MaxAccessTokenTTL())
+}
+
+// RevokeOtherSessions signs out all of the user's sessions except keep and
+// returns how many were revoked
+func RevokeOtherSessions(userID, keep uint) (int, error) {
+	var sessions []models.Session
+	if err := ActiveSessionsQuery(userID).Where("id <> ?", keep).Find(&sessions).Error; err != nil {
+		return 0, err
+	}
+
+	for i := range sessions {
+		if err := RevokeSession(&sessions[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(sessions), nil
+}
+
+// DescribeDevice turns a user agent into a short label like "Chrome on Windows"
+func DescribeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "Unknown device"
+	}
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	case strings.Contains(ua, "python"):
+		browser = "Python"
+	}
+
+	platform := ""
+	switch {
+	case strings.Contains(ua, "android"):
+		platform = "Android"
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		platform = "iOS"
+	case strings.Contains(ua, "windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "mac os"):
+		platform = "macOS"
+	case strings.Contains(ua, "linux"):
+		platform = "Linux"
+	}
+
+	if platform == "" {
+		return browser
+	}
+	return browser + " on " + platform
+}
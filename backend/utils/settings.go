@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+// SettingTwoFactorRoles lists the roles that must enroll in two-factor auth
+const SettingTwoFactorRoles = "2fa_required_roles"
+
+// GetSetting returns a setting value or the default when it is not set
+func GetSetting(key, defaultValue string) string {
+	var setting models.Setting
+	if err := config.DB.Where("`key` = ?", key).First(&setting).Error; err != nil {
+		return defaultValue
+	}
+	return setting.Value
+}
+
+// SetSetting creates or updates a setting
+func SetSetting(key, value string) error {
+	return config.DB.Clauses(clause.OnConflict{
+		UpdateAll: true,
+	}).Create(&models.Setting{Key: key, Value: value}).Error
+}
+
+// TwoFactorRequiredRoles returns the roles that admins forced onto 2FA
+func TwoFactorRequiredRoles() []string {
+	value := GetSetting(SettingTwoFactorRoles, "")
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
+
+// IsTwoFactorRequired reports whether users with the role must use 2FA
+func IsTwoFactorRequired(role string) bool {
+	for _, r := range TwoFactorRequiredRoles() {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,285 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Limits that keep a smart filter cheap to run
+const (
+	MaxTodoQueryLength = 500
+	maxTodoQueryTerms  = 20
+)
+
+// Keys a TodoQueryTerm can have
+const (
+	TodoQueryTag      = "tag"
+	TodoQueryStatus   = "status"
+	TodoQueryPriority = "priority"
+	TodoQueryList     = "list"
+	TodoQueryDue      = "due"
+	TodoQueryBefore   = "before"
+	TodoQueryAfter    = "after"
+	TodoQueryText     = "text"
+)
+
+// Relative due windows understood by due:
+var TodoDueWindows = map[string]bool{
+	"today":      true,
+	"tomorrow":   true,
+	"this-week":  true,
+	"next-week":  true,
+	"this-month": true,
+	"overdue":    true,
+	"none":       true,
+	"any":        true,
+}
+
+// TagNamePattern is what a tag may look like once normalized
+var TagNamePattern = regexp.MustCompile(`^[\p{L}\p{N}_-]{1,50}$`)
+
+var todoQueryDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// TodoQueryTerm is one condition of a smart filter. Values are validated
+// by ParseTodoQuery, so callers only need to map them to SQL.
+type TodoQueryTerm struct {
+	Key    string
+	Value  string
+	Negate bool
+}
+
+// NormalizeTagName lowercases a tag and strips a leading #
+func NormalizeTagName(name string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+}
+
+// ParseTodoQuery parses the smart filter syntax. Terms are separated by
+// spaces and must all match:
+//
+//	#kuliah               has the tag kuliah
+//	is:done, is:pending   status; is:overdue means pending past due
+//	status:done           same as is:done
+//	priority:high,medium  any of the priorities
+//	list:12, list:none    in a list, or in none
+//	due:this-week         today, tomorrow, this-week, next-week, this-month,
+//	                      overdue, none, any or a YYYY-MM-DD date
+//	before:2025-01-31     due before a date; after: is due on or after it
+//	"exam prep", word     text in the title or description
+//
+// A term prefixed with - or preceded by "not" is negated, and "and" between
+// terms is allowed for readability, so "#kuliah and due:this-week and not
+// is:done" works.
+func ParseTodoQuery(s string) ([]TodoQueryTerm, error) {
+	if len(s) > MaxTodoQueryLength {
+		return nil, fmt.Errorf("query must be at most %d characters", MaxTodoQueryLength)
+	}
+
+	tokens, err := splitTodoQuery(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var terms []TodoQueryTerm
+	negate := false
+	for _, tok := range tokens {
+		if !tok.quoted {
+			switch strings.ToLower(tok.text) {
+			case "and":
+				continue
+			case "not":
+				negate = !negate
+				continue
+			}
+		}
+
+		term, err := parseTodoQueryToken(tok)
+		if err != nil {
+			return nil, err
+		}
+		term.Negate = term.Negate != negate
+		negate = false
+		terms = append(terms, term)
+	}
+	if negate {
+		return nil, fmt.Errorf("query ends with \"not\"")
+	}
+	if len(terms) > maxTodoQueryTerms {
+		return nil, fmt.Errorf("query can have at most %d terms", maxTodoQueryTerms)
+	}
+	return terms, nil
+}
+
+type todoQueryToken struct {
+	text   string
+	quoted bool
+	// negate is set for -"quoted phrase"
+	negate bool
+}
+
+// splitTodoQuery splits on spaces, keeping "quoted phrases" together
+func splitTodoQuery(s string) ([]todoQueryToken, error) {
+	var tokens []todoQueryToken
+	var cur strings.Builder
+	inQuote, quoted, negate := false, false, false
+
+	flush := func() {
+		if cur.Len() > 0 || quoted {
+			tokens = append(tokens, todoQueryToken{text: cur.String(), quoted: quoted, negate: negate})
+		}
+		cur.Reset()
+		quoted, negate = false, false
+	}
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			if !inQuote && !quoted && cur.String() == "-" {
+				cur.Reset()
+				negate = true
+			}
+			inQuote = !inQuote
+			quoted = true
+		case unicode.IsSpace(r) && !inQuote:
+			flush()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("query has an unclosed quote")
+	}
+	flush()
+	return tokens, nil
+}
+
+func parseTodoQueryToken(tok todoQueryToken) (TodoQueryTerm, error) {
+	text := tok.text
+	term := TodoQueryTerm{Negate: tok.negate}
+	if strings.HasPrefix(text, "-") && len(text) > 1 && !tok.quoted {
+		term.Negate = true
+		text = text[1:]
+	}
+
+	if tok.quoted {
+		return textTerm(term, text)
+	}
+
+	if strings.HasPrefix(text, "#") {
+		name := NormalizeTagName(text)
+		if !TagNamePattern.MatchString(name) {
+			return term, fmt.Errorf("invalid tag %q", text)
+		}
+		term.Key, term.Value = TodoQueryTag, name
+		return term, nil
+	}
+
+	key, value, ok := strings.Cut(text, ":")
+	if !ok {
+		return textTerm(term, text)
+	}
+	key, value = strings.ToLower(key), strings.ToLower(value)
+
+	switch key {
+	case "is", TodoQueryStatus:
+		switch value {
+		case "done", "pending":
+			term.Key, term.Value = TodoQueryStatus, value
+		case "overdue":
+			if key != "is" {
+				return term, fmt.Errorf("status must be done or pending")
+			}
+			term.Key, term.Value = TodoQueryDue, "overdue"
+		default:
+			return term, fmt.Errorf("unknown %s:%s", key, value)
+		}
+	case TodoQueryPriority:
+		for _, p := range strings.Split(value, ",") {
+			if p != "low" && p != "medium" && p != "high" {
+				return term, fmt.Errorf("priority must be low, medium or high")
+			}
+		}
+		term.Key, term.Value = TodoQueryPriority, value
+	case TodoQueryList:
+		if value != "none" && !isDigits(value) {
+			return term, fmt.Errorf("list must be a list ID or none")
+		}
+		term.Key, term.Value = TodoQueryList, value
+	case TodoQueryDue:
+		if !TodoDueWindows[value] && !isQueryDate(value) {
+			return term, fmt.Errorf("unknown due:%s", value)
+		}
+		term.Key, term.Value = TodoQueryDue, value
+	case TodoQueryBefore, TodoQueryAfter:
+		if !isQueryDate(value) {
+			return term, fmt.Errorf("%s needs a YYYY-MM-DD date", key)
+		}
+		term.Key, term.Value = key, value
+	case "tag":
+		name := NormalizeTagName(value)
+		if !TagNamePattern.MatchString(name) {
+			return term, fmt.Errorf("invalid tag %q", value)
+		}
+		term.Key, term.Value = TodoQueryTag, name
+	default:
+		return term, fmt.Errorf("unknown filter %q", key+":")
+	}
+	return term, nil
+}
+
+func textTerm(term TodoQueryTerm, text string) (TodoQueryTerm, error) {
+	if strings.TrimSpace(text) == "" {
+		return term, fmt.Errorf("query has an empty phrase")
+	}
+	term.Key, term.Value = TodoQueryText, text
+	return term, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isQueryDate(s string) bool {
+	if !todoQueryDate.MatchString(s) {
+		return false
+	}
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+// TodoDueRange returns the [from, to) range a due window covers, in loc.
+// ok is false for windows that aren't a range: overdue, none and any.
+func TodoDueRange(window string, now time.Time, loc *time.Location) (from, to time.Time, ok bool) {
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	// Weeks start on Monday
+	monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+
+	switch window {
+	case "today":
+		return today, today.AddDate(0, 0, 1), true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), today.AddDate(0, 0, 2), true
+	case "this-week":
+		return monday, monday.AddDate(0, 0, 7), true
+	case "next-week":
+		return monday.AddDate(0, 0, 7), monday.AddDate(0, 0, 14), true
+	case "this-month":
+		first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+		return first, first.AddDate(0, 1, 0), true
+	}
+	if d, err := time.ParseInLocation("2006-01-02", window, loc); err == nil {
+		return d, d.AddDate(0, 0, 1), true
+	}
+	return time.Time{}, time.Time{}, false
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTodoQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []TodoQueryTerm
+	}{
+		{name: "empty", query: "  ", want: nil},
+		{name: "tag", query: "#Kuliah", want: []TodoQueryTerm{{Key: TodoQueryTag, Value: "kuliah"}}},
+		{name: "tag key", query: "tag:exam", want: []TodoQueryTerm{{Key: TodoQueryTag, Value: "exam"}}},
+		{name: "status", query: "is:done status:pending", want: []TodoQueryTerm{
+			{Key: TodoQueryStatus, Value: "done"},
+			{Key: TodoQueryStatus, Value: "pending"},
+		}},
+		{name: "overdue is a due window", query: "is:overdue", want: []TodoQueryTerm{{Key: TodoQueryDue, Value: "overdue"}}},
+		{name: "priorities", query: "priority:HIGH,medium", want: []TodoQueryTerm{{Key: TodoQueryPriority, Value: "high,medium"}}},
+		{name: "list", query: "list:12 list:none", want: []TodoQueryTerm{
+			{Key: TodoQueryList, Value: "12"},
+			{Key: TodoQueryList, Value: "none"},
+		}},
+		{name: "dates", query: "due:2026-02-28 before:2026-03-01 after:2026-01-01", want: []TodoQueryTerm{
+			{Key: TodoQueryDue, Value: "2026-02-28"},
+			{Key: TodoQueryBefore, Value: "2026-03-01"},
+			{Key: TodoQueryAfter, Value: "2026-01-01"},
+		}},
+		{name: "words and phrases", query: `read "exam prep"`, want: []TodoQueryTerm{
+			{Key: TodoQueryText, Value: "read"},
+			{Key: TodoQueryText, Value: "exam prep"},
+		}},
+		{name: "and is skipped", query: "#kuliah and due:this-week", want: []TodoQueryTerm{
+			{Key: TodoQueryTag, Value: "kuliah"},
+			{Key: TodoQueryDue, Value: "this-week"},
+		}},
+		{name: "not negates the next term only", query: "not is:done #kuliah", want: []TodoQueryTerm{
+			{Key: TodoQueryStatus, Value: "done", Negate: true},
+			{Key: TodoQueryTag, Value: "kuliah"},
+		}},
+		{name: "minus negates", query: "-#kuliah -priority:low", want: []TodoQueryTerm{
+			{Key: TodoQueryTag, Value: "kuliah", Negate: true},
+			{Key: TodoQueryPriority, Value: "low", Negate: true},
+		}},
+		{name: "minus before a phrase", query: `-"group work"`, want: []TodoQueryTerm{
+			{Key: TodoQueryText, Value: "group work", Negate: true},
+		}},
+		{name: "double negation", query: "not -is:done", want: []TodoQueryTerm{
+			{Key: TodoQueryStatus, Value: "done"},
+		}},
+		{name: "quoted keywords are text", query: `"not" "is:done"`, want: []TodoQueryTerm{
+			{Key: TodoQueryText, Value: "not"},
+			{Key: TodoQueryText, Value: "is:done"},
+		}},
+		{name: "lone minus is text", query: "-", want: []TodoQueryTerm{
+			{Key: TodoQueryText, Value: "-"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTodoQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseTodoQuery(%q): %v", tt.query, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTodoQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTodoQueryErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "unclosed quote", query: `"exam prep`},
+		{name: "empty phrase", query: `""`},
+		{name: "trailing not", query: "#kuliah not"},
+		{name: "unknown key", query: "owner:me"},
+		{name: "unknown status", query: "is:archived"},
+		{name: "status overdue", query: "status:overdue"},
+		{name: "unknown priority", query: "priority:urgent"},
+		{name: "list name", query: "list:kuliah"},
+		{name: "unknown due window", query: "due:someday"},
+		{name: "invalid date", query: "before:2026-02-30"},
+		{name: "invalid tag", query: "#a/b"},
+		{name: "too long", query: strings.Repeat("a", MaxTodoQueryLength+1)},
+		{name: "too many terms", query: strings.Repeat("word ", maxTodoQueryTerms+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if terms, err := ParseTodoQuery(tt.query); err == nil {
+				t.Errorf("ParseTodoQuery(%q) = %+v, want an error", tt.query, terms)
+			}
+		})
+	}
+}
+
+func TestTodoDueRange(t *testing.T) {
+	jakarta, _ := time.LoadLocation("Asia/Jakarta")
+	day := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, jakarta)
+	}
+	// Thursday evening in UTC is already Friday in Jakarta
+	now := time.Date(2026, 1, 15, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		window   string
+		from, to time.Time
+		ok       bool
+	}{
+		{window: "today", from: day(2026, 1, 16), to: day(2026, 1, 17), ok: true},
+		{window: "tomorrow", from: day(2026, 1, 17), to: day(2026, 1, 18), ok: true},
+		{window: "this-week", from: day(2026, 1, 12), to: day(2026, 1, 19), ok: true},
+		{window: "next-week", from: day(2026, 1, 19), to: day(2026, 1, 26), ok: true},
+		{window: "this-month", from: day(2026, 1, 1), to: day(2026, 2, 1), ok: true},
+		{window: "2026-02-28", from: day(2026, 2, 28), to: day(2026, 3, 1), ok: true},
+		{window: "overdue"},
+		{window: "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			from, to, ok := TodoDueRange(tt.window, now, jakarta)
+			if ok != tt.ok {
+				t.Fatalf("TodoDueRange(%q) ok = %v, want %v", tt.window, ok, tt.ok)
+			}
+			if ok && (!from.Equal(tt.from) || !to.Equal(tt.to)) {
+				t.Errorf("TodoDueRange(%q) = [%s, %s), want [%s, %s)", tt.window, from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package utils
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrInvalidRefreshToken is returned when a refresh token is unknown,
+// expired or has already been used
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// ErrInvalidLoginCode is returned when a one-time login code is unknown,
+// expired or already exchanged
+var ErrInvalidLoginCode = errors.New("invalid or expired login code")
+
+// LoginCodeTTL is how long the frontend has to exchange a login code
+var LoginCodeTTL = time.Minute
+
+// ErrTokenRevoked is returned when an access token was revoked by logout
+// or by a user-wide sign-out
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+var storeCtx = context.Background()
+
+// refreshTokenRecord is what Redis keeps for every outstanding refresh token
+type refreshTokenRecord struct {
+	UserID    uint `json:"user_id"`
+	SessionID uint `json:"session_id"`
+}
+
+func refreshTokenKey(hash string) string {
+	return "refresh_token:" + hash
+}
+
+func userRefreshTokensKey(userID uint) string {
+	return fmt.Sprintf("user_refresh_tokens:%d", userID)
+}
+
+func revokedJTIKey(jti string) string {
+	return "revoked_jti:" + jti
+}
+
+func tokensValidAfterKey(userID uint) string {
+	return fmt.Sprintf("tokens_valid_after:%d", userID)
+}
+
+func loginCodeKey(hash string) string {
+	return "login_code:" + hash
+}
+
+// IssueRefreshToken creates an opaque refresh token for the user's session
+// and stores its hash in Redis. The raw token is only ever returned to the client.
+func IssueRefreshToken(userID, sessionID uint) (string, error) {
+	token := GenerateSecureToken(32)
+	hash := HashToken(token)
+
+	record, err := json.Marshal(refreshTokenRecord{UserID: userID, SessionID: sessionID})
+	if err != nil {
+		return "", err
+	}
+
+	_, err = config.RedisClient.TxPipelined(storeCtx, func(pipe redis.Pipeliner) error {
+		pipe.Set(storeCtx, refreshTokenKey(hash), record, RefreshTokenTTL)
+		pipe.SAdd(storeCtx, userRefreshTokensKey(userID), hash)
+		pipe.Expire(storeCtx, userRefreshTokensKey(userID), RefreshTokenTTL)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ConsumeRefreshToken atomically removes a refresh token and returns the
+// user and session it belonged to. A token can therefore only be rotated once.
+func ConsumeRefreshToken(token string) (userID, sessionID uint, err error) {
+	hash := HashToken(token)
+
+	val, err := config.RedisClient.GetDel(storeCtx, refreshTokenKey(hash)).Result()
+	if err == redis.Nil {
+		return 0, 0, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var record refreshTokenRecord
+	if err := json.Unmarshal([]byte(val), &record); err != nil {
+		return 0, 0, ErrInvalidRefreshToken
+	}
+
+	config.RedisClient.SRem(storeCtx, userRefreshTokensKey(record.UserID), hash)
+	return record.UserID, record.SessionID, nil
+}
+
+// RevokeRefreshToken deletes one of the user's refresh tokens so it can no
+// longer be used. Unknown tokens and tokens of other users are left alone.
+func RevokeRefreshToken(userID uint, token string) error {
+	hash := HashToken(token)
+
+	val, err := config.RedisClient.Get(storeCtx, refreshTokenKey(hash)).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var record refreshTokenRecord
+	if err := json.Unmarshal([]byte(val), &record); err != nil || record.UserID != userID {
+		return nil
+	}
+
+	_, err = config.RedisClient.TxPipelined(storeCtx, func(pipe redis.Pipeliner) error {
+		pipe.Del(storeCtx, refreshTokenKey(hash))
+		pipe.SRem(storeCtx, userRefreshTokensKey(userID), hash)
+		return nil
+	})
+	return err
+}
+
+// IssueLoginCode creates a short-lived one-time code that the frontend
+// exchanges for tokens, so tokens never travel in a redirect URL
+func IssueLoginCode(userID uint) (string, error) {
+	code := GenerateSecureToken(32)
+	if err := config.CacheSet(loginCodeKey(HashToken(code)), userID, LoginCodeTTL); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// ConsumeLoginCode atomically removes a login code and returns its user
+func ConsumeLoginCode(code string) (uint, error) {
+	userID, err := config.RedisClient.GetDel(storeCtx, loginCodeKey(HashToken(code))).Uint64()
+	if err == redis.Nil {
+		return 0, ErrInvalidLoginCode
+	}
+	if err != nil {
+		return 0, err
+	}
+	return uint(userID), nil
+}
+
+// RevokeAccessToken blacklists the token's jti until the token expires
+func RevokeAccessToken(claims *Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+
+	return config.CacheSet(revokedJTIKey(claims.ID), "1", ttl)
+}
+
+// RevokeUserTokens signs the user out everywhere: every session is revoked,
+// every refresh token is deleted and access tokens issued before now stop
+// being accepted
+func RevokeUserTokens(userID uint) error {
+	if err := config.DB.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+
+	hashes, err := config.RedisClient.SMembers(storeCtx, userRefreshTokensKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	_, err = config.RedisClient.TxPipelined(storeCtx, func(pipe redis.Pipeliner) error {
+		for _, hash := range hashes {
+			pipe.Del(storeCtx, refreshTokenKey(hash))
+		}
+		pipe.Del(storeCtx, userRefreshTokensKey(userID))
+		// Access tokens live at most MaxAccessTokenTTL, so the cut-off can expire with them
+		pipe.Set(storeCtx, tokensValidAfterKey(userID), time.Now().UnixMilli(), MaxAccessTokenTTL())
+		return nil
+	})
+	return err
+}
+
+// CheckAccessToken returns ErrTokenRevoked if the token's jti or session
+// was revoked or the token was issued before the last sign-out everywhere
+// of its user or, for impersonation tokens, of the impersonating admin
+func CheckAccessToken(claims *Claims) error {
+	var jtiCmd, sessionCmd *redis.IntCmd
+	var validAfterCmd, impersonatorCmd *redis.StringCmd
+
+	_, err := config.RedisClient.Pipelined(storeCtx, func(pipe redis.Pipeliner) error {
+		if claims.ID != "" {
+			jtiCmd = pipe.Exists(storeCtx, revokedJTIKey(claims.ID))
+		}
+		if claims.SessionID != 0 {
+			sessionCmd = pipe.Exists(storeCtx, revokedSessionKey(claims.SessionID))
+		}
+		validAfterCmd = pipe.Get(storeCtx, tokensValidAfterKey(claims.UserID))
+		if claims.ImpersonatorID != 0 {
+			impersonatorCmd = pipe.Get(storeCtx, tokensValidAfterKey(claims.ImpersonatorID))
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	if jtiCmd != nil && jtiCmd.Val() > 0 {
+		return ErrTokenRevoked
+	}
+	if sessionCmd != nil && sessionCmd.Val() > 0 {
+		return ErrTokenRevoked
+	}
+	if issuedBefore(claims, validAfterCmd) {
+		return ErrTokenRevoked
+	}
+	if impersonatorCmd != nil && issuedBefore(claims, impersonatorCmd) {
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// issuedBefore reports whether the token predates the tokens_valid_after
+// cut-off loaded by cmd. A missing cut-off never rejects.
+func issuedBefore(claims *Claims, cmd *redis.StringCmd) bool {
+	validAfter, err := cmd.Int64()
+	if err != nil {
+		return false
+	}
+	return claims.IssuedAt == nil || claims.IssuedAt.Time.UnixMilli() < validAfter
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"bulan2-backend/config"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestCheckAccessToken(t *testing.T) {
+	mr := miniredis.RunT(t)
+	config.RedisClient = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { config.RedisClient.Close() })
+
+	issued := time.Now().Add(-time.Minute)
+	before := strconv.FormatInt(issued.Add(-time.Second).UnixMilli(), 10)
+	after := strconv.FormatInt(issued.Add(time.Second).UnixMilli(), 10)
+
+	tests := []struct {
+		name   string
+		claims Claims
+		keys   map[string]string
+		want   error
+	}{
+		{name: "plain token", claims: Claims{UserID: 1}},
+		{name: "user signed out earlier", claims: Claims{UserID: 1},
+			keys: map[string]string{tokensValidAfterKey(1): before}},
+		{name: "user signed out later", claims: Claims{UserID: 1},
+			keys: map[string]string{tokensValidAfterKey(1): after}, want: ErrTokenRevoked},
+		{name: "other user signed out", claims: Claims{UserID: 1},
+			keys: map[string]string{tokensValidAfterKey(2): after}},
+		{name: "impersonator signed out earlier", claims: Claims{UserID: 1, ImpersonatorID: 2},
+			keys: map[string]string{tokensValidAfterKey(2): before}},
+		{name: "impersonator signed out later", claims: Claims{UserID: 1, ImpersonatorID: 2},
+			keys: map[string]string{tokensValidAfterKey(2): after}, want: ErrTokenRevoked},
+		{name: "revoked jti", claims: Claims{UserID: 1, RegisteredClaims: jwt.RegisteredClaims{ID: "jti-1"}},
+			keys: map[string]string{revokedJTIKey("jti-1"): "1"}, want: ErrTokenRevoked},
+		{name: "revoked session", claims: Claims{UserID: 1, SessionID: 7},
+			keys: map[string]string{revokedSessionKey(7): "1"}, want: ErrTokenRevoked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr.FlushAll()
+			for k, v := range tt.keys {
+				mr.Set(k, v)
+			}
+			claims := tt.claims
+			claims.IssuedAt = jwt.NewNumericDate(issued)
+
+			if err := CheckAccessToken(&claims); err != tt.want {
+				t.Errorf("CheckAccessToken() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package utils
+
+import (
+	"bulan2-backend/config"
+	"bulan2-backend/models"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Upload directories that hold user files
+const (
+	GalleryUploadDir = "./uploads/gallery"
+	ProfileUploadDir = "./uploads/profiles"
+)
+
+// UserDataSource is one kind of data that references a user. The schema has
+// no foreign keys, so every table holding a user ID must be listed in
+// UserDataSources or it will be missed by exports and account purges.
+type UserDataSource struct {
+	// Name is the file name (without .json) used in the export archive
+	Name string
+	// Export loads the user's rows, including soft-deleted ones
+	Export func(db *gorm.DB, userID uint) (interface{}, error)
+	// Files returns uploaded files that belong to the user's rows
+	Files func(db *gorm.DB, userID uint) ([]string, error)
+	// Purge removes or anonymizes the user's rows
+	Purge func(tx *gorm.DB, userID uint) error
+}
+
+// exportRows loads every T row where column equals the user ID
+func exportRows[T any](column string) func(db *gorm.DB, userID uint) (interface{}, error) {
+	return func(db *gorm.DB, userID uint) (interface{}, error) {
+		var rows []T
+		err := db.Unscoped().Where(column+" = ?", userID).Find(&rows).Error
+		return rows, err
+	}
+}
+
+// purgeRows hard-deletes every T row where column equals the user ID
+func purgeRows[T any](column string) func(tx *gorm.DB, userID uint) error {
+	return func(tx *gorm.DB, userID uint) error {
+		return tx.Unscoped().Where(column+" = ?", userID).Delete(new(T)).Error
+	}
+}
+
+// UserDataSources lists every table that references a user
+var UserDataSources = []UserDataSource{
+	{
+		Name: "todos",
+		Export: func(db *gorm.DB, userID uint) (interface{}, error) {
+			var rows []models.Todo
+			err := db.Unscoped().Preload("Tags").Where("user_id = ?", userID).Find(&rows).Error
+			return rows, err
+		},
+		Purge: purgeRows[models.Todo]("user_id"),
+	},
+	{
+		Name:   "tags",
+		Export: exportRows[models.Tag]("user_id"),
+		Purge: func(tx *gorm.DB, userID uint) error {
+			tags := tx.Model(&models.Tag{}).Select("id").Where("user_id = ?", userID)
+			if err := tx.Exec("DELETE FROM todo_tags WHERE tag_id IN (?)", tags).Error; err != nil {
+				return err
+			}
+			return tx.Where("user_id = ?", userID).Delete(&models.Tag{}).Error
+		},
+	},
+	{
+		Name:   "saved_filters",
+		Export: exportRows[models.SavedFilter]("user_id"),
+		Purge:  purgeRows[models.SavedFilter]("user_id"),
+	},
+	{
+		Name:   "todo_series",
+		Export: exportRows[models.TodoSeries]("user_id"),
+		Purge:  purgeRows[models.TodoSeries]("user_id"),
+	},
+	{
+		Name:   "todo_lists",
+		Export: exportRows[models.TodoList]("user_id"),
+		Purge:  purgeRows[models.TodoList]("user_id"),
+	},
+	{
+		Name:   "comments",
+		Export: exportRows[models.Comment]("user_id"),
+		Purge:  purgeRows[models.Comment]("user_id"),
+	},
+	{
+		Name:   "gallery",
+		Export: exportRows[models.Gallery]("uploader"),
+		Files: func(db *gorm.DB, userID uint) ([]string, error) {
+			var photos []models.Gallery
+			if err := db.Unscoped().Where("uploader = ?", userID).Find(&photos).Error; err != nil {
+				return nil, err
+			}
+			files := make([]string, 0, len(photos))
+			for _, p := range photos {
+				files = append(files, filepath.Join(GalleryUploadDir, filepath.Base(p.Filename)))
+			}
+			return files, nil
+		},
+		Purge: purgeRows[models.Gallery]("uploader"),
+	},
+	{
+		Name: "assignment_submissions",
+		Export: func(db *gorm.DB, userID uint) (interface{}, error) {
+			var submissions []models.AssignmentSubmission
+			err := db.Preload("Assignment", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+				Where("mahasiswa_id = ?", userID).Find(&submissions).Error
+			return submissions, err
+		},
+		Purge: purgeRows[models.AssignmentSubmission]("mahasiswa_id"),
+	},
+	{
+		// Assignments stay after a purge because students' submissions and
+		// grades hang off them; they keep pointing at the anonymized user
+		Name:   "assignments_created",
+		Export: exportRows[models.Assignment]("guru_id"),
+	},
+	{
+		Name: "mentorships",
+		Export: func(db *gorm.DB, userID uint) (interface{}, error) {
+			var rows []models.MahasiswaGuru
+			err := db.Where("mahasiswa_id = ? OR guru_id = ?", userID, userID).Find(&rows).Error
+			return rows, err
+		},
+		Purge: func(tx *gorm.DB, userID uint) error {
+			return tx.Where("mahasiswa_id = ? OR guru_id = ?", userID, userID).Delete(&models.MahasiswaGuru{}).Error
+		},
+	},
+	{
+		Name:   "identities",
+		Export: exportRows[models.UserIdentity]("user_id"),
+		Purge:  purgeRows[models.UserIdentity]("user_id"),
+	},
+	{
+		Name:   "sessions",
+		Export: exportRows[models.Session]("user_id"),
+		Purge:  purgeRows[models.Session]("user_id"),
+	},
+	{
+		// Requests made while an admin acted as the user, or by the user as
+		// an admin. They are part of the audit trail, so a purge keeps them
+		// pointing at the anonymized user.
+		Name: "impersonation_logs",
+		Export: func(db *gorm.DB, userID uint) (interface{}, error) {
+			var rows []models.ImpersonationLog
+			err := db.Where("user_id = ? OR impersonator_id = ?", userID, userID).Order("id ASC").Find(&rows).Error
+			return rows, err
+		},
+	},
+	{
+		// Actions by, on behalf of or on the user. Like impersonation_logs
+		// they are kept as audit record when the user is purged.
+		Name: "audit_events",
+		Export: func(db *gorm.DB, userID uint) (interface{}, error) {
+			var rows []models.AuditEvent
+			err := db.Where("actor_id = ? OR impersonator_id = ? OR (target_type = ? AND target_id = ?)", userID, userID, "user", userID).
+				Order("id ASC").Find(&rows).Error
+			return rows, err
+		},
+	},
+	{
+		Name:   "personal_access_tokens",
+		Export: exportRows[models.PersonalAccessToken]("user_id"),
+		Purge:  purgeRows[models.PersonalAccessToken]("user_id"),
+	},
+	{
+		// Secrets only; nothing worth exporting
+		Name:  "recovery_codes",
+		Purge: purgeRows[models.RecoveryCode]("user_id"),
+	},
+	{
+		Name:  "password_resets",
+		Purge: purgeRows[models.PasswordReset]("user_id"),
+	},
+}
+
+// UserFiles returns every uploaded file that belongs to the user
+func UserFiles(db *gorm.DB, user *models.User) ([]string, error) {
+	var files []string
+	for _, src := range UserDataSources {
+		if src.Files == nil {
+			continue
+		}
+		paths, err := src.Files(db, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src.Name, err)
+		}
+		files = append(files, paths...)
+	}
+
+	// Google profile pictures are remote URLs, uploads are file names
+	if user.Foto != "" && !strings.HasPrefix(user.Foto, "http://") && !strings.HasPrefix(user.Foto, "https://") {
+		files = append(files, filepath.Join(ProfileUploadDir, filepath.Base(user.Foto)))
+	}
+	return files, nil
+}
+
+// PurgeUserData removes everything the user owns and turns the user row into
+// an anonymous tombstone, so records other users depend on (assignments,
+// grades) still resolve. Uploaded files are removed after the commit.
+func PurgeUserData(userID uint) error {
+	var user models.User
+	if err := config.DB.Unscoped().First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	files, err := UserFiles(config.DB, &user)
+	if err != nil {
+		return err
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		for _, src := range UserDataSources {
+			if src.Purge == nil {
+				continue
+			}
+			if err := src.Purge(tx, userID); err != nil {
+				return fmt.Errorf("%s: %w", src.Name, err)
+			}
+		}
+
+		anonymized := models.User{Password: GenerateSecureToken(32)}
+		if err := anonymized.HashPassword(); err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Model(&user).Updates(map[string]interface{}{
+			"nama":          "Deleted user",
+			"email":         fmt.Sprintf("deleted-%d@deleted.invalid", userID),
+			"password":      anonymized.Password,
+			"passwordless":  true,
+			"status":        "suspended",
+			"foto":          "",
+			"pending_email": "",
+			"totp_secret":   "",
+			"totp_enabled":  false,
+			"delete_after":  nil,
+			"deleted_at":    gorm.Expr("COALESCE(deleted_at, NOW())"),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, path := range files {
+		DeleteFile(path)
+	}
+
+	// Redis state keyed by user or email
+	RevokeUserTokens(userID)
+	ResetLoginFailures(user.Email)
+	return nil
+}
@@ -0,0 +1,8 @@
+-- Email Verification Migration
+-- Adds verified_at to users and treats accounts that existed before
+-- verification was introduced as verified
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS verified_at DATETIME DEFAULT NULL;
+
+UPDATE users SET verified_at = created_at WHERE verified_at IS NULL;
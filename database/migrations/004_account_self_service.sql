@@ -0,0 +1,11 @@
+-- Account Self-Service Migration
+-- Adds passwordless and pending_email to users. Accounts created by Google
+-- login before identities were tracked got a random password nobody knows;
+-- they are the only ones whose photo is a remote URL (uploads store a file
+-- name), so they are marked passwordless and can set a local password.
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS passwordless BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS pending_email VARCHAR(100) DEFAULT NULL;
+
+UPDATE users SET passwordless = TRUE WHERE foto LIKE 'http%';
@@ -0,0 +1,16 @@
+-- Todo Details Migration
+-- Adds description, due date, priority and completion time to todos.
+-- Todos already marked done get their creation time as completion time,
+-- the best guess available, so "completed" filters and sorting include them.
+
+ALTER TABLE todo
+    ADD COLUMN IF NOT EXISTS description TEXT,
+    ADD COLUMN IF NOT EXISTS due_date DATETIME DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS priority ENUM('low', 'medium', 'high') NOT NULL DEFAULT 'medium',
+    ADD COLUMN IF NOT EXISTS completed_at DATETIME DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS updated_at DATETIME DEFAULT NULL;
+
+UPDATE todo SET completed_at = created_at WHERE status = 'done' AND completed_at IS NULL;
+UPDATE todo SET updated_at = created_at WHERE updated_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_todos_user_due ON todo(user_id, due_date);
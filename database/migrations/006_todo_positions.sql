@@ -0,0 +1,23 @@
+-- Todo Positions Migration
+-- Adds the manual order rank used by drag-and-drop. Existing top-level todos
+-- are ranked per user and list in their current created_at DESC order, so
+-- the manual order starts out the way todos were listed before. Ranks are
+-- base-62 strings compared byte by byte, hence the binary collation; the
+-- trailing 'V' keeps them from ending in '0' as the ranking code requires.
+
+ALTER TABLE todo
+    ADD COLUMN IF NOT EXISTS position VARCHAR(191) CHARACTER SET ascii COLLATE ascii_bin NOT NULL DEFAULT '';
+
+UPDATE todo
+JOIN (
+    SELECT id, ROW_NUMBER() OVER (
+        PARTITION BY user_id, list_id
+        ORDER BY created_at DESC, id DESC
+    ) AS rn
+    FROM todo
+    WHERE parent_id IS NULL
+) ranked ON ranked.id = todo.id
+SET todo.position = CONCAT('a', LPAD(ranked.rn, 9, '0'), 'V')
+WHERE todo.position = '';
+
+CREATE INDEX IF NOT EXISTS idx_todos_position ON todo(user_id, list_id, position);
@@ -1,8 +1,10 @@
 'use client';
 
-import { useEffect, useState } from 'react';
+import { useEffect, useRef, useState } from 'react';
 import { useRouter, useSearchParams } from 'next/navigation';
 import { useAuthStore } from '@/store/authStore';
+import api from '@/lib/api';
+import type { AuthResponse } from '@/types';
 
 export default function GoogleCallbackPage() {
     const router = useRouter();
@@ -10,47 +12,52 @@ export default function GoogleCallbackPage() {
     const { setAuth } = useAuthStore();
     const [error, setError] = useState('');
     const [processing, setProcessing] = useState(true);
+    // The code is single-use, so make sure it is only exchanged once
+    const exchanged = useRef(false);
 
     useEffect(() => {
-        const handleCallback = () => {
-            try {
-                // Extract data from URL params
-                const token = searchParams.get('token');
-                const userId = searchParams.get('userId');
-                const email = searchParams.get('email');
-                const nama = searchParams.get('nama');
-                const role = searchParams.get('role');
-                const foto = searchParams.get('foto');
+        const handleCallback = async () => {
+            if (exchanged.current) {
+                return;
+            }
+            exchanged.current = true;
 
-                if (!token || !userId || !email || !nama || !role) {
+            try {
+                // The backend only sends a one-time code; tokens are fetched via POST
+                const code = searchParams.get('code');
+                if (!code) {
                     setError('Invalid callback data');
                     setProcessing(false);
                     return;
                 }
 
-                // Create user object
-                const user = {
-                    id: parseInt(userId),
-                    email: decodeURIComponent(email),
-                    nama: decodeURIComponent(nama),
-                    role: role as 'user' | 'admin',
-                    foto: foto ? decodeURIComponent(foto) : '',
-                };
+                const response = await api.post('/auth/oauth/exchange', { code });
+
+                if (response.data.mfa_required || response.data.mfa_setup_required) {
+                    setError('Two-factor authentication is required for this account');
+                    setProcessing(false);
+                    return;
+                }
+
+                const { token, refresh_token, user } = response.data as AuthResponse;
 
                 // Save to auth store
-                setAuth(user, token);
+                setAuth(user, token, refresh_token);
+                setProcessing(false);
 
                 // Redirect based on role
                 setTimeout(() => {
                     if (user.role === 'admin') {
                         router.push('/admin/dashboard');
+                    } else if (user.role === 'guru') {
+                        router.push('/guru/dashboard');
                     } else {
                         router.push('/user/dashboard');
                     }
                 }, 1000);
-            } catch (err) {
+            } catch (err: any) {
                 console.error('OAuth callback error:', err);
-                setError('Failed to process login');
+                setError(err.response?.data?.error || 'Failed to process login');
                 setProcessing(false);
             }
         };
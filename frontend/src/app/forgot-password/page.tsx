@@ -0,0 +1,59 @@
+'use client';
+
+import { useState } from 'react';
+import Link from 'next/link';
+import api from '@/lib/api';
+import styles from '../login/login.module.css';
+
+export default function ForgotPasswordPage() {
+    const [email, setEmail] = useState('');
+    const [message, setMessage] = useState('');
+    const [error, setError] = useState('');
+    const [loading, setLoading] = useState(false);
+
+    const handleSubmit = async (e: React.FormEvent) => {
+        e.preventDefault();
+        setError('');
+        setMessage('');
+        setLoading(true);
+
+        try {
+            const response = await api.post('/auth/forgot-password', { email });
+            setMessage(response.data.message);
+        } catch (err: any) {
+            setError(err.response?.data?.error || 'Gagal mengirim link reset password');
+        } finally {
+            setLoading(false);
+        }
+    };
+
+    return (
+        <div className={styles.container}>
+            <div className={styles.card}>
+                <h2>Lupa Password</h2>
+
+                {error && <p className={styles.error}>{error}</p>}
+                {message && <p>{message}</p>}
+
+                <form onSubmit={handleSubmit}>
+                    <label>Email</label>
+                    <input
+                        type="email"
+                        value={email}
+                        onChange={(e) => setEmail(e.target.value)}
+                        placeholder="email@example.com"
+                        required
+                    />
+
+                    <button type="submit" className={styles.btn} disabled={loading}>
+                        {loading ? 'Loading...' : 'Kirim Link Reset'}
+                    </button>
+
+                    <div className={styles.link}>
+                        <Link href="/login">Kembali ke Login</Link>
+                    </div>
+                </form>
+            </div>
+        </div>
+    );
+}
@@ -15,6 +15,7 @@ export default function LoginPage() {
     const [password, setPassword] = useState('');
     const [error, setError] = useState('');
     const [loading, setLoading] = useState(false);
+    const [notice, setNotice] = useState('');
 
     const handleSubmit = async (e: React.FormEvent) => {
         e.preventDefault();
@@ -27,8 +28,8 @@ export default function LoginPage() {
                 password,
             });
 
-            const { token, user } = response.data;
-            setAuth(user, token);
+            const { token, refresh_token, user } = response.data;
+            setAuth(user, token, refresh_token);
 
             // Redirect based on role
             if (user.role === 'admin') {
@@ -45,6 +46,26 @@ export default function LoginPage() {
         }
     };
 
+    const handleMagicLink = async () => {
+        setError('');
+        setNotice('');
+        if (!email) {
+            setError('Masukkan email terlebih dahulu');
+            return;
+        }
+
+        setLoading(true);
+        try {
+            // withCredentials stores the cookie that binds the link to this browser
+            const response = await api.post('/auth/magic-link', { email }, { withCredentials: true });
+            setNotice(response.data.message);
+        } catch (err: any) {
+            setError(err.response?.data?.error || 'Gagal mengirim link login');
+        } finally {
+            setLoading(false);
+        }
+    };
+
     const handleGoogleLogin = () => {
         // Redirect to backend OAuth endpoint
         const apiUrl = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
@@ -57,6 +78,7 @@ export default function LoginPage() {
                 <h2>Login</h2>
 
                 {error && <p className={styles.error}>{error}</p>}
+                {notice && <p>{notice}</p>}
 
                 <form onSubmit={handleSubmit}>
                     <label>Email</label>
@@ -83,6 +105,15 @@ export default function LoginPage() {
 
                     <div className={styles.divider}>atau</div>
 
+                    <button
+                        type="button"
+                        className={styles.googleBtn}
+                        onClick={handleMagicLink}
+                        disabled={loading}
+                    >
+                        Kirim link login ke email
+                    </button>
+
                     <button
                         type="button"
                         className={styles.googleBtn}
@@ -98,6 +129,10 @@ export default function LoginPage() {
                         Sign in with Google
                     </button>
 
+                    <div className={styles.link}>
+                        <Link href="/forgot-password">Lupa password?</Link>
+                    </div>
+
                     <div className={styles.link}>
                         Belum punya akun? <Link href="/register">Daftar</Link>
                     </div>
@@ -0,0 +1,115 @@
+'use client';
+
+import { useEffect, useRef, useState } from 'react';
+import { useRouter, useSearchParams } from 'next/navigation';
+import { useAuthStore } from '@/store/authStore';
+import api from '@/lib/api';
+import type { AuthResponse } from '@/types';
+
+export default function MagicLinkPage() {
+    const router = useRouter();
+    const searchParams = useSearchParams();
+    const { setAuth } = useAuthStore();
+    const [error, setError] = useState('');
+    const [processing, setProcessing] = useState(true);
+    // The link is single-use, so make sure it is only submitted once
+    const submitted = useRef(false);
+
+    useEffect(() => {
+        const handleLogin = async () => {
+            if (submitted.current) {
+                return;
+            }
+            submitted.current = true;
+
+            try {
+                const token = searchParams.get('token');
+                if (!token) {
+                    setError('Invalid login link');
+                    setProcessing(false);
+                    return;
+                }
+
+                // The binding cookie proves this is the browser that asked for the link
+                const response = await api.post('/auth/magic-link/login', { token }, { withCredentials: true });
+
+                if (response.data.mfa_required || response.data.mfa_setup_required) {
+                    setError('Two-factor authentication is required for this account');
+                    setProcessing(false);
+                    return;
+                }
+
+                const { token: accessToken, refresh_token, user } = response.data as AuthResponse;
+
+                setAuth(user, accessToken, refresh_token);
+                setProcessing(false);
+
+                // Redirect based on role
+                setTimeout(() => {
+                    if (user.role === 'admin') {
+                        router.push('/admin/dashboard');
+                    } else if (user.role === 'guru') {
+                        router.push('/guru/dashboard');
+                    } else {
+                        router.push('/user/dashboard');
+                    }
+                }, 1000);
+            } catch (err: any) {
+                setError(err.response?.data?.error || 'Failed to process login');
+                setProcessing(false);
+            }
+        };
+
+        handleLogin();
+    }, [searchParams, setAuth, router]);
+
+    const containerStyle = {
+        display: 'flex',
+        justifyContent: 'center',
+        alignItems: 'center',
+        height: '100vh',
+        background: '#eef1f5',
+    };
+
+    const cardStyle = {
+        width: '400px',
+        background: '#fff',
+        padding: '35px',
+        borderRadius: '15px',
+        boxShadow: '0px 5px 20px rgba(0, 0, 0, 0.1)',
+        textAlign: 'center' as const,
+    };
+
+    if (error) {
+        return (
+            <div style={containerStyle}>
+                <div style={cardStyle}>
+                    <h2>Login Error</h2>
+                    <p style={{ color: '#dc3545', marginTop: '20px' }}>{error}</p>
+                    <a href="/login" style={{
+                        display: 'inline-block',
+                        marginTop: '20px',
+                        padding: '12px 24px',
+                        background: '#4e73df',
+                        color: 'white',
+                        borderRadius: '8px',
+                        textDecoration: 'none'
+                    }}>
+                        Back to Login
+                    </a>
+                </div>
+            </div>
+        );
+    }
+
+    return (
+        <div style={containerStyle}>
+            <div style={cardStyle}>
+                <h2>Processing Login...</h2>
+                <p style={{ marginTop: '20px' }}>
+                    {processing ? 'Please wait...' : 'Redirecting...'}
+                </p>
+            </div>
+        </div>
+    );
+}
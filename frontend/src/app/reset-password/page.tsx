@@ -0,0 +1,58 @@
+'use client';
+
+import { useState } from 'react';
+import { useRouter, useSearchParams } from 'next/navigation';
+import api from '@/lib/api';
+import styles from '../login/login.module.css';
+
+export default function ResetPasswordPage() {
+    const router = useRouter();
+    const searchParams = useSearchParams();
+    const [password, setPassword] = useState('');
+    const [error, setError] = useState('');
+    const [loading, setLoading] = useState(false);
+
+    const handleSubmit = async (e: React.FormEvent) => {
+        e.preventDefault();
+        setError('');
+        setLoading(true);
+
+        try {
+            await api.post('/auth/reset-password', {
+                token: searchParams.get('token'),
+                password,
+            });
+            router.push('/login');
+        } catch (err: any) {
+            setError(err.response?.data?.error || 'Gagal reset password');
+        } finally {
+            setLoading(false);
+        }
+    };
+
+    return (
+        <div className={styles.container}>
+            <div className={styles.card}>
+                <h2>Reset Password</h2>
+
+                {error && <p className={styles.error}>{error}</p>}
+
+                <form onSubmit={handleSubmit}>
+                    <label>Password Baru</label>
+                    <input
+                        type="password"
+                        value={password}
+                        onChange={(e) => setPassword(e.target.value)}
+                        placeholder="Minimal 6 karakter"
+                        minLength={6}
+                        required
+                    />
+
+                    <button type="submit" className={styles.btn} disabled={loading}>
+                        {loading ? 'Loading...' : 'Simpan Password'}
+                    </button>
+                </form>
+            </div>
+        </div>
+    );
+}
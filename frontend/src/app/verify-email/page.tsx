@@ -0,0 +1,42 @@
+'use client';
+
+import { useEffect, useState } from 'react';
+import Link from 'next/link';
+import { useSearchParams } from 'next/navigation';
+import api from '@/lib/api';
+import styles from '../login/login.module.css';
+
+export default function VerifyEmailPage() {
+    const searchParams = useSearchParams();
+    const [message, setMessage] = useState('Memverifikasi email...');
+    const [error, setError] = useState('');
+
+    useEffect(() => {
+        const token = searchParams.get('token');
+        if (!token) {
+            setError('Link verifikasi tidak valid');
+            return;
+        }
+
+        // Links for an email change confirm the new address instead
+        const endpoint = searchParams.get('change') ? '/auth/confirm-email-change' : '/auth/verify-email';
+
+        api.post(endpoint, { token })
+            .then((response) => setMessage(response.data.message))
+            .catch((err) => setError(err.response?.data?.error || 'Verifikasi email gagal'));
+    }, [searchParams]);
+
+    return (
+        <div className={styles.container}>
+            <div className={styles.card}>
+                <h2>Verifikasi Email</h2>
+
+                {error ? <p className={styles.error}>{error}</p> : <p>{message}</p>}
+
+                <div className={styles.link}>
+                    <Link href="/login">Kembali ke Login</Link>
+                </div>
+            </div>
+        </div>
+    );
+}
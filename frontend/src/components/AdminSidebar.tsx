@@ -3,6 +3,7 @@
 import Link from 'next/link';
 import { usePathname } from 'next/navigation';
 import { useAuthStore } from '@/store/authStore';
+import { logout } from '@/lib/api';
 import { useRouter } from 'next/navigation';
 import styles from './AdminSidebar.module.css';
 
@@ -11,7 +12,8 @@ export default function AdminSidebar() {
     const { clearAuth } = useAuthStore();
     const router = useRouter();
 
-    const handleLogout = () => {
+    const handleLogout = async () => {
+        await logout();
         clearAuth();
         router.push('/login');
     };
@@ -4,7 +4,7 @@ import Link from 'next/link';
 import { usePathname, useRouter } from 'next/navigation';
 import { useState, useRef, useEffect } from 'react';
 import { useAuthStore } from '@/store/authStore';
-import api from '@/lib/api';
+import api, { logout } from '@/lib/api';
 import styles from './GuruSidebar.module.css';
 
 export default function GuruSidebar() {
@@ -16,7 +16,8 @@ export default function GuruSidebar() {
     const [pendingCount, setPendingCount] = useState(0);
     const fileInputRef = useRef<HTMLInputElement>(null);
 
-    const handleLogout = () => {
+    const handleLogout = async () => {
+        await logout();
         clearAuth();
         router.push('/login');
     };
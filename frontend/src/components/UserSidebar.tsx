@@ -4,7 +4,7 @@ import Link from 'next/link';
 import { usePathname, useRouter } from 'next/navigation';
 import { useState, useRef, useEffect } from 'react';
 import { useAuthStore } from '@/store/authStore';
-import api from '@/lib/api';
+import api, { logout } from '@/lib/api';
 import styles from './UserSidebar.module.css';
 
 export default function UserSidebar() {
@@ -15,7 +15,8 @@ export default function UserSidebar() {
     const [sidebarOpen, setSidebarOpen] = useState(false);
     const fileInputRef = useRef<HTMLInputElement>(null);
 
-    const handleLogout = () => {
+    const handleLogout = async () => {
+        await logout();
         clearAuth();
         router.push('/login');
     };
@@ -24,20 +24,65 @@ api.interceptors.request.use(
     (error) => Promise.reject(error)
 );
 
+// Shared in-flight refresh so parallel 401s only rotate the token once
+let refreshPromise: Promise<string> | null = null;
+
+const refreshAccessToken = (): Promise<string> => {
+    if (!refreshPromise) {
+        const refreshToken = localStorage.getItem('refreshToken');
+        refreshPromise = (refreshToken
+            ? axios.post(`${API_URL}/api/auth/refresh`, { refresh_token: refreshToken })
+            : Promise.reject(new Error('No refresh token'))
+        )
+            .then((response) => {
+                localStorage.setItem('token', response.data.token);
+                localStorage.setItem('refreshToken', response.data.refresh_token);
+                return response.data.token as string;
+            })
+            .finally(() => {
+                refreshPromise = null;
+            });
+    }
+    return refreshPromise;
+};
+
 // Response interceptor for error handling
 api.interceptors.response.use(
     (response) => response,
-    (error) => {
-        if (error.response?.status === 401) {
-            // Unauthorized - clear token and redirect to login
-            if (typeof window !== 'undefined') {
-                localStorage.removeItem('token');
-                localStorage.removeItem('user');
-                window.location.href = '/login';
+    async (error) => {
+        const original = error.config;
+        if (error.response?.status === 401 && typeof window !== 'undefined') {
+            // Try to rotate the refresh token once before giving up
+            if (original && !original._retry && !original.url?.startsWith('/auth/')) {
+                original._retry = true;
+                try {
+                    const token = await refreshAccessToken();
+                    original.headers.Authorization = `Bearer ${token}`;
+                    return api(original);
+                } catch {
+                    // fall through to logout
+                }
             }
+
+            // Unauthorized - clear token and redirect to login
+            localStorage.removeItem('token');
+            localStorage.removeItem('refreshToken');
+            localStorage.removeItem('user');
+            window.location.href = '/login';
         }
         return Promise.reject(error);
     }
 );
 
+// logout revokes the current session on the server before local state is cleared
+export const logout = async () => {
+    try {
+        await api.post('/auth/logout', {
+            refresh_token: localStorage.getItem('refreshToken') || undefined,
+        });
+    } catch {
+        // The session is cleared locally either way
+    }
+};
+
 export default api;
@@ -5,7 +5,8 @@ import type { User } from '@/types';
 interface AuthState {
     user: User | null;
     token: string | null;
-    setAuth: (user: User, token: string) => void;
+    refreshToken: string | null;
+    setAuth: (user: User, token: string, refreshToken?: string) => void;
     updateUser: (user: User) => void;
     clearAuth: () => void;
     isAuthenticated: () => boolean;
@@ -17,11 +18,15 @@ export const useAuthStore = create<AuthState>()(
         (set, get) => ({
             user: null,
             token: null,
-            setAuth: (user, token) => {
-                set({ user, token });
+            refreshToken: null,
+            setAuth: (user, token, refreshToken) => {
+                set({ user, token, refreshToken: refreshToken ?? null });
                 if (typeof window !== 'undefined') {
                     localStorage.setItem('token', token);
                     localStorage.setItem('user', JSON.stringify(user));
+                    if (refreshToken) {
+                        localStorage.setItem('refreshToken', refreshToken);
+                    }
                 }
             },
             updateUser: (user) => {
@@ -31,9 +36,10 @@ export const useAuthStore = create<AuthState>()(
                 }
             },
             clearAuth: () => {
-                set({ user: null, token: null });
+                set({ user: null, token: null, refreshToken: null });
                 if (typeof window !== 'undefined') {
                     localStorage.removeItem('token');
+                    localStorage.removeItem('refreshToken');
                     localStorage.removeItem('user');
                 }
             },
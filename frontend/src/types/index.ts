@@ -6,6 +6,7 @@ export interface User {
     email: string;
     role: 'admin' | 'user' | 'guru';
     foto?: string;
+    verified?: boolean;
 }
 
 export interface Student {
@@ -29,11 +30,75 @@ export interface Todo {
     id: number;
     user_id: number;
     title: string;
+    description: string;
+    list_id: number | null;
+    parent_id: number | null;
+    sort_order: number;
+    position: string;
+    subtask_count: number;
+    subtasks_done: number;
+    progress: number | null;
+    subtasks?: Todo[];
     status: 'pending' | 'done';
+    priority: 'low' | 'medium' | 'high';
+    due_date: string | null;
+    completed_at: string | null;
+    overdue: boolean;
+    series_id: number | null;
+    occurrence_at: string | null;
+    series?: TodoSeries;
+    tags: Tag[] | null;
     created_at: string;
+    updated_at: string;
     user?: User;
 }
 
+export interface Tag {
+    id: number;
+    user_id: number;
+    name: string;
+    todo_count?: number;
+    created_at: string;
+}
+
+export interface SavedFilter {
+    id: number;
+    user_id: number;
+    name: string;
+    query: string;
+    sort_order: number;
+    created_at: string;
+    updated_at: string;
+}
+
+export interface TodoSeries {
+    id: number;
+    user_id: number;
+    rrule: string;
+    time_zone: string;
+    starts_at: string;
+    title: string;
+    description: string;
+    priority: 'low' | 'medium' | 'high';
+    list_id: number | null;
+    ended_at: string | null;
+    created_at: string;
+    updated_at: string;
+}
+
+export interface TodoList {
+    id: number;
+    user_id: number;
+    name: string;
+    color: string;
+    archived: boolean;
+    sort_order: number;
+    pending_count: number;
+    done_count: number;
+    created_at: string;
+    updated_at: string;
+}
+
 export interface Comment {
     id: number;
     user_id: number;
@@ -81,6 +146,8 @@ export interface AssignmentSubmission {
 export interface AuthResponse {
     message: string;
     token: string;
+    refresh_token: string;
+    expires_in: number;
     user: User;
 }
 